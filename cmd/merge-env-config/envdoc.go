@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	config "github.com/kayac/go-config"
+)
+
+// envVar is an environment variable referenced by templates.
+type envVar struct {
+	Name       string
+	Required   bool // referenced by must_env
+	Default    string
+	HasDefault bool
+	Refs       []config.EnvRef
+}
+
+// envVars aggregates `refs` by variable names in the order of first appearance.
+// Dynamic refs are skipped.
+func envVars(refs []config.EnvRef) []*envVar {
+	var vars []*envVar
+	byName := make(map[string]*envVar)
+	for _, ref := range refs {
+		for _, key := range ref.Keys {
+			v, ok := byName[key]
+			if !ok {
+				v = &envVar{Name: key}
+				byName[key] = v
+				vars = append(vars, v)
+			}
+			v.Refs = append(v.Refs, ref)
+			if ref.Func == "must_env" {
+				v.Required = true
+			}
+			if ref.HasDefault && !v.HasDefault {
+				v.Default, v.HasDefault = ref.Default, true
+			}
+		}
+	}
+	return vars
+}
+
+var plainEnvValue = regexp.MustCompile(`^[A-Za-z0-9_./:@,+-]*$`)
+
+// writeEnvExample writes a .env.example of variables referenced by `refs`.
+func writeEnvExample(w io.Writer, refs []config.EnvRef) {
+	fmt.Fprintln(w, "# Generated by merge-env-config -env-example")
+	for _, ref := range refs {
+		if ref.Dynamic {
+			fmt.Fprintf(w, "# %s:%d: %s with a non-constant name is not listed\n", ref.Source, ref.Line, ref.Func)
+		}
+	}
+	for _, v := range envVars(refs) {
+		fmt.Fprintln(w)
+		locs := make([]string, 0, len(v.Refs))
+		for _, ref := range v.Refs {
+			locs = append(locs, fmt.Sprintf("%s:%d", ref.Source, ref.Line))
+		}
+		if v.Required {
+			fmt.Fprintf(w, "# required. used at %s\n", strings.Join(locs, ", "))
+		} else {
+			fmt.Fprintf(w, "# used at %s\n", strings.Join(locs, ", "))
+		}
+		value := v.Default
+		if !plainEnvValue.MatchString(value) {
+			value = strconv.Quote(value)
+		}
+		fmt.Fprintf(w, "%s=%s\n", v.Name, value)
+	}
+}
+
+// writeEnvMarkdown writes Markdown tables of variables referenced by `refs` for each template file.
+func writeEnvMarkdown(w io.Writer, refs []config.EnvRef) {
+	var sources []string
+	bySource := make(map[string][]config.EnvRef)
+	for _, ref := range refs {
+		if _, ok := bySource[ref.Source]; !ok {
+			sources = append(sources, ref.Source)
+		}
+		bySource[ref.Source] = append(bySource[ref.Source], ref)
+	}
+	for i, src := range sources {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "## %s\n\n", src)
+		fmt.Fprintln(w, "| Variable | Required | Default | Lines |")
+		fmt.Fprintln(w, "|----------|----------|---------|-------|")
+		for _, v := range envVars(bySource[src]) {
+			required, def := "no", ""
+			if v.Required {
+				required = "yes"
+			}
+			if v.HasDefault {
+				def = "`" + strings.Replace(v.Default, "|", `\|`, -1) + "`"
+			}
+			lines := make([]string, 0, len(v.Refs))
+			for _, ref := range v.Refs {
+				lines = append(lines, strconv.Itoa(ref.Line))
+			}
+			fmt.Fprintf(w, "| `%s` | %s | %s | %s |\n", v.Name, required, def, strings.Join(lines, ", "))
+		}
+		for _, ref := range bySource[src] {
+			if ref.Dynamic {
+				fmt.Fprintf(w, "\n`%s` at line %d has a non-constant name.\n", ref.Func, ref.Line)
+			}
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	config "github.com/kayac/go-config"
+)
+
+var testEnvRefs = []config.EnvRef{
+	{Func: "env", Keys: []string{"APP_DOMAIN", "APP_HOST"}, Default: "example.com", HasDefault: true, Source: "app.yaml", Line: 1},
+	{Func: "must_env", Keys: []string{"DB_PASSWORD"}, Source: "app.yaml", Line: 3},
+	{Func: "env", Keys: []string{"APP_DOMAIN"}, Default: "a b|c", HasDefault: true, Source: "parts/db.yaml", Line: 2},
+	{Func: "env", Dynamic: true, Source: "app.yaml", Line: 5},
+	{Func: "env", Keys: []string{"GREETING"}, Default: "hello world", HasDefault: true, Source: "parts/db.yaml", Line: 3},
+	{Func: "must_env", Keys: []string{"GREETING"}, Source: "parts/db.yaml", Line: 4},
+}
+
+func TestWriteEnvExample(t *testing.T) {
+	var b bytes.Buffer
+	writeEnvExample(&b, testEnvRefs)
+	expected := `# Generated by merge-env-config -env-example
+# app.yaml:5: env with a non-constant name is not listed
+
+# used at app.yaml:1, parts/db.yaml:2
+APP_DOMAIN=example.com
+
+# used at app.yaml:1
+APP_HOST=example.com
+
+# required. used at app.yaml:3
+DB_PASSWORD=
+
+# required. used at parts/db.yaml:3, parts/db.yaml:4
+GREETING="hello world"
+`
+	if diff := cmp.Diff(expected, b.String()); diff != "" {
+		t.Errorf("unexpected output (-want +got):\n%s", diff)
+	}
+}
+
+func TestWriteEnvMarkdown(t *testing.T) {
+	var b bytes.Buffer
+	writeEnvMarkdown(&b, testEnvRefs)
+	expected := "## app.yaml\n" +
+		"\n" +
+		"| Variable | Required | Default | Lines |\n" +
+		"|----------|----------|---------|-------|\n" +
+		"| `APP_DOMAIN` | no | `example.com` | 1 |\n" +
+		"| `APP_HOST` | no | `example.com` | 1 |\n" +
+		"| `DB_PASSWORD` | yes |  | 3 |\n" +
+		"\n" +
+		"`env` at line 5 has a non-constant name.\n" +
+		"\n" +
+		"## parts/db.yaml\n" +
+		"\n" +
+		"| Variable | Required | Default | Lines |\n" +
+		"|----------|----------|---------|-------|\n" +
+		"| `APP_DOMAIN` | no | `a b\\|c` | 2 |\n" +
+		"| `GREETING` | yes | `hello world` | 3, 4 |\n"
+	if diff := cmp.Diff(expected, b.String()); diff != "" {
+		t.Errorf("unexpected output (-want +got):\n%s", diff)
+	}
+}
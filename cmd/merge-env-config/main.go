@@ -1,11 +1,17 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
+	"sort"
+	"strings"
 
 	config "github.com/kayac/go-config"
+	"gopkg.in/yaml.v2"
 )
 
 var Version = "current"
@@ -14,14 +20,52 @@ type Loader func(interface{}, ...string) error
 
 type Marshaler func(interface{}) ([]byte, error)
 
+// stringsFlag is a flag which can be set multiple times.
+type stringsFlag []string
+
+func (s *stringsFlag) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringsFlag) Set(v string) error {
+	*s = append(*s, v)
+	return nil
+}
+
+// cliFS opens files in the OS file system, and "-" as stdin.
+type cliFS struct{}
+
+func (cliFS) Open(name string) (fs.File, error) {
+	if name == "-" {
+		return os.Stdin, nil
+	}
+	return os.Open(name)
+}
+
 func main() {
 	os.Exit(_main())
 }
 
 func _main() int {
-	var isJSON, showVersion bool
+	if len(os.Args) > 1 && os.Args[1] == "schema" {
+		return schemaMain(os.Args[2:])
+	}
+
+	var isJSON, showVersion, mustMatch, explain, check, listEnv, envExample, envMarkdown bool
+	var schemaPath string
+	var envFiles stringsFlag
+	var envFileOverride bool
 
 	flag.BoolVar(&isJSON, "json", false, "file(s) is JSON")
+	flag.BoolVar(&mustMatch, "must-match", false, "error when a glob pattern matches no files")
+	flag.Var(&envFiles, "env-file", "read environment variables for templates from the dotenv file (can be repeated)")
+	flag.BoolVar(&envFileOverride, "env-file-override", false, "variables in -env-file override the process environment")
+	flag.StringVar(&schemaPath, "schema", "", "validate merged config by the JSON Schema file")
+	flag.BoolVar(&check, "check", false, "report all missing environment variables required by must_env, without printing config")
+	flag.BoolVar(&listEnv, "list-env", false, "list env and must_env calls in templates without rendering them")
+	flag.BoolVar(&envExample, "env-example", false, "print .env.example of env and must_env calls in templates")
+	flag.BoolVar(&envMarkdown, "env-markdown", false, "print Markdown tables of env and must_env calls in each template")
+	flag.BoolVar(&explain, "explain", false, "print merged config in YAML annotated with the origins of values")
 	flag.BoolVar(&showVersion, "v", false, "show version number")
 	flag.BoolVar(&showVersion, "version", false, "show version number")
 	flag.Parse()
@@ -41,22 +85,79 @@ func _main() int {
 	var (
 		load    Loader
 		marshal Marshaler
+		format  string
 		conf    map[string]interface{}
+		prov    config.Provenance
 	)
 
+	loader := config.New()
+	loader.FS = cliFS{}
+	loader.GlobMustMatch = mustMatch
+	loader.DotenvOverride = envFileOverride
+	if err := loader.LoadDotenv(envFiles...); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if schemaPath != "" {
+		schema, err := loader.LoadSchema(schemaPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		loader.Schema = schema
+	}
 	if isJSON {
-		load = config.LoadWithEnvJSON
+		load = loader.LoadWithEnvJSON
 		marshal = config.MarshalJSON
+		format = "json"
 	} else {
-		load = config.LoadWithEnv
+		load = loader.LoadWithEnv
 		marshal = config.Marshal
+		format = "yaml"
 	}
 
-	err := load(&conf, args...)
+	paths, err := loader.Glob(args...)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		return 1
 	}
+	if listEnv || envExample || envMarkdown {
+		refs, err := loader.EnvRefs(paths...)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		switch {
+		case envExample:
+			writeEnvExample(os.Stdout, refs)
+		case envMarkdown:
+			writeEnvMarkdown(os.Stdout, refs)
+		default:
+			for _, ref := range refs {
+				fmt.Println(ref)
+			}
+		}
+		return 0
+	}
+	if explain {
+		prov, err = loader.LoadAsWithProvenance(format, &conf, paths...)
+	} else {
+		err = load(&conf, paths...)
+	}
+	if check {
+		return reportMissingEnv(err)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if explain {
+		if err := printExplain(os.Stdout, conf, prov, "", ""); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return 0
+	}
 	b, err := marshal(&conf)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
@@ -65,9 +166,117 @@ func _main() int {
 	return 0
 }
 
+// schemaMain prints a JSON Schema of the type registered by config.RegisterSchemaType.
+// Register types in an init function of this package to build your own merge-env-config.
+func schemaMain(args []string) int {
+	flags := flag.NewFlagSet("schema", flag.ExitOnError)
+	tag := flags.String("tag", "yaml", "struct tag to name keys (yaml, json or toml)")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, `Usage of merge-env-config schema:
+
+  merge-env-config schema [-tag yaml] TYPE
+
+registered types:`, config.SchemaTypes())
+		flags.PrintDefaults()
+		return 1
+	}
+	b, err := config.GenerateRegisteredSchema(flags.Arg(0), *tag)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	os.Stdout.Write(append(b, '\n'))
+	return 0
+}
+
+// reportMissingEnv prints missing environment variables in `err`, and returns the exit code.
+func reportMissingEnv(err error) int {
+	if err == nil {
+		return 0
+	}
+	var missing *config.MissingEnvsError
+	if !errors.As(err, &missing) {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	for _, e := range missing.Errors {
+		fmt.Println(e.Error())
+	}
+	return 2
+}
+
+// printExplain prints the map `v` in YAML, with comments of the origins of values.
+func printExplain(w io.Writer, v map[string]interface{}, prov config.Provenance, path, indent string) error {
+	keys := make([]string, 0, len(v))
+	for k := range v {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		p := k
+		if path != "" {
+			p = path + "." + k
+		}
+		if m := toStringMap(v[k]); len(m) > 0 {
+			name, err := yaml.Marshal(k)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(w, "%s%s:\n", indent, strings.TrimSpace(string(name)))
+			if err := printExplain(w, m, prov, p, indent+"  "); err != nil {
+				return err
+			}
+			continue
+		}
+		b, err := yaml.Marshal(yaml.MapSlice{{Key: k, Value: v[k]}})
+		if err != nil {
+			return err
+		}
+		lines := strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+		if o, ok := prov[p]; ok {
+			lines[0] += "  # " + formatOrigin(o)
+		}
+		for _, line := range lines {
+			fmt.Fprintln(w, indent+line)
+		}
+	}
+	return nil
+}
+
+// toStringMap returns `v` as map[string]interface{}, or nil if `v` is not a map.
+func toStringMap(v interface{}) map[string]interface{} {
+	switch m := v.(type) {
+	case map[string]interface{}:
+		return m
+	case map[interface{}]interface{}:
+		sm := make(map[string]interface{}, len(m))
+		for k, e := range m {
+			sm[fmt.Sprint(k)] = e
+		}
+		return sm
+	}
+	return nil
+}
+
+func formatOrigin(o config.Origin) string {
+	s := o.Source
+	if o.Line > 0 {
+		s = fmt.Sprintf("%s:%d", s, o.Line)
+	}
+	if o.Template {
+		s += " (template)"
+	}
+	return s
+}
+
 func printUsage() {
 	fmt.Fprintln(os.Stderr, `Usage of merge-env-config:
 
-  merge-env-config [-json] config1.yaml [config2.yaml ...]`)
+  merge-env-config [-json] [-env-file .env] [-schema schema.json] [-explain | -check | -list-env | -env-example | -env-markdown] config1.yaml [config2.yaml ...]
+  merge-env-config schema [-tag yaml] TYPE
+
+  "-" as a config file reads from stdin.
+  Glob patterns (e.g. 'conf.d/*.yaml', 'conf.d/**/*.yaml') are expanded in lexical order.`)
 	flag.PrintDefaults()
 }
@@ -5,13 +5,12 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io/fs"
 	"io/ioutil"
-	"os"
-	"strings"
+	"reflect"
 	"sync"
 	"text/template"
 
-	"github.com/BurntSushi/toml"
 	"gopkg.in/yaml.v2"
 )
 
@@ -19,9 +18,16 @@ func init() {
 	defaultLoader = New()
 }
 
-type customFunc func(data []byte) ([]byte, error)
+type customFunc func(data []byte, src source) ([]byte, error)
 
-type unmarshaler func([]byte, interface{}) error
+// source represents where a config document is read from.
+type source struct {
+	fsys fs.FS
+	name string // empty for bytes and readers
+
+	includedBy []string     // stack of including files
+	render     *renderState // shared with included files
+}
 
 func ReadWithEnv(configPath string) ([]byte, error) {
 	return defaultLoader.ReadWithEnv(configPath)
@@ -33,6 +39,8 @@ func ReadWithEnvBytes(b []byte) ([]byte, error) {
 
 // Load loads YAML files from `configPaths`.
 // and assigns decoded values into the `conf` value.
+// Values in later files are deep-merged into values in earlier files.
+// See Loader.SetMergeStrategy to change how values are merged.
 func Load(conf interface{}, configPaths ...string) error {
 	return defaultLoader.Load(conf, configPaths...)
 }
@@ -104,49 +112,197 @@ func MarshalJSON(v interface{}) ([]byte, error) {
 	return json.MarshalIndent(v, "", "  ")
 }
 
-func loadWithFunc(conf interface{}, configPaths []string, custom customFunc, unmarshal unmarshaler) error {
+func (l *Loader) loadWithFunc(conf interface{}, configPaths []string, custom customFunc, c codec) error {
+	return l.loadFilesWithFunc(conf, l.FS, configPaths, custom, func(string) (codec, error) {
+		return c, nil
+	})
+}
+
+// loadFilesWithFunc loads files in fsys with the codec chosen by `codecOf` for each path.
+// The merged tree is decoded by the codec of the first path.
+func (l *Loader) loadFilesWithFunc(conf interface{}, fsys fs.FS, configPaths []string, custom customFunc, codecOf func(string) (codec, error)) error {
+	docs, tree, c, err := l.mergeFiles(fsys, configPaths, custom, codecOf)
+	if err != nil {
+		return err
+	}
+	return l.decode(conf, docs, tree, c)
+}
+
+// mergeFiles reads files in fsys with the codec chosen by `codecOf` for each path,
+// and returns the documents, the merged tree and the codec of the first path.
+func (l *Loader) mergeFiles(fsys fs.FS, configPaths []string, custom customFunc, codecOf func(string) (codec, error)) ([]document, interface{}, codec, error) {
+	m := l.newMerger()
+	var (
+		tree    interface{}
+		base    *codec
+		docs    []document
+		missing MissingEnvsError
+	)
 	for _, configPath := range configPaths {
-		err := loadConfig(conf, configPath, custom, unmarshal)
+		c, err := codecOf(configPath)
 		if err != nil {
-			return err
+			return nil, nil, codec{}, err
+		}
+		if base == nil {
+			base = &c
+		}
+		doc, err := loadConfig(source{fsys: fsys, name: configPath}, custom, c)
+		if missing.add(err) {
+			continue // report with missing variables in other files
 		}
+		if err != nil {
+			return nil, nil, codec{}, err
+		}
+		docs = append(docs, doc)
+		tree = mergeTree(m, tree, copyTree(doc.tree))
 	}
-	return nil
+	if len(missing.Errors) > 0 {
+		return nil, nil, codec{}, &missing
+	}
+	if base == nil {
+		return nil, nil, builtinCodecs["yaml"], nil
+	}
+	return docs, tree, *base, nil
 }
 
-func loadConfig(conf interface{}, configPath string, custom customFunc, unmarshal unmarshaler) error {
-	data, err := ioutil.ReadFile(configPath)
+func (l *Loader) loadBytesWithFunc(conf interface{}, src []byte, custom customFunc, c codec) error {
+	doc, err := loadConfigBytes(src, source{fsys: l.FS}, custom, c)
 	if err != nil {
-		return fmt.Errorf("%s read failed: %w", configPath, err)
+		return err
 	}
-	if err := loadConfigBytes(conf, data, custom, unmarshal); err != nil {
-		return fmt.Errorf("%s load failed: %w", configPath, err)
+	return l.decode(conf, []document{doc}, doc.tree, c)
+}
+
+// document is a config document rendered and decoded into a generic tree.
+type document struct {
+	name  string
+	raw   []byte // before rendering
+	data  []byte // after rendering
+	tree  interface{}
+	codec codec
+}
+
+func loadConfig(src source, custom customFunc, c codec) (document, error) {
+	data, err := readFile(src.fsys, src.name)
+	if err != nil {
+		return document{}, fmt.Errorf("%s read failed: %w", src.name, err)
 	}
-	return nil
+	doc, err := loadConfigBytes(data, src, custom, c)
+	if err != nil {
+		return document{}, fmt.Errorf("%s load failed: %w", src.name, err)
+	}
+	return doc, nil
 }
 
-func loadConfigBytes(conf interface{}, data []byte, custom customFunc, unmarshal unmarshaler) error {
-	data, err := readConfigBytes(data, custom)
+func readFile(fsys fs.FS, name string) ([]byte, error) {
+	if fsys == nil {
+		return ioutil.ReadFile(name)
+	}
+	return fs.ReadFile(fsys, name)
+}
+
+// loadConfigBytes renders raw and decodes it into a generic tree.
+func loadConfigBytes(raw []byte, src source, custom customFunc, c codec) (document, error) {
+	data, err := readConfigBytes(raw, src, custom)
+	if err != nil {
+		return document{}, err
+	}
+	tree, err := c.tree(data)
 	if err != nil {
+		return document{}, fmt.Errorf("parse failed: %w", err)
+	}
+	return document{name: src.name, raw: raw, data: data, tree: tree, codec: c}, nil
+}
+
+func mergeTree(m *merger, tree, v interface{}) interface{} {
+	if v == nil { // empty document
+		return tree
+	}
+	if tree == nil {
+		return v
+	}
+	return m.merge("", tree, v)
+}
+
+// decode assigns the merged tree of `docs` into the `conf` value and runs post-load passes.
+func (l *Loader) decode(conf interface{}, docs []document, tree interface{}, c codec) error {
+	if l.Strict {
+		if err := checkUnknownKeys(conf, docs, c.name); err != nil {
+			return err
+		}
+	}
+	if l.Schema != nil && len(docs) > 0 {
+		if err := l.Schema.validate(resolveTree(tree), docs); err != nil {
+			return err
+		}
+	}
+	nilPtrs := make(map[uintptr]bool)
+	if isStructPtr(conf) {
+		if err := applyDefaults(reflect.ValueOf(conf).Elem(), "", true, nilPtrs); err != nil {
+			return err
+		}
+	}
+	if len(docs) == 1 {
+		// a single document is decoded as it is, without merging
+		if err := c.dec.Decode(docs[0].data, conf); err != nil {
+			if docs[0].name != "" {
+				return fmt.Errorf("%s load failed: parse failed: %w", docs[0].name, err)
+			}
+			return fmt.Errorf("parse failed: %w", err)
+		}
+	} else if err := l.decodeTree(conf, docs, tree, c); err != nil {
 		return err
 	}
-	if err := unmarshal(data, conf); err != nil {
-		return fmt.Errorf("parse failed: %w", err)
+	return l.postLoad(conf, docs, c, nilPtrs)
+}
+
+// postLoad runs passes after decoding.
+// `nilPtrs` has the addresses of pointers which were nil before decoding.
+func (l *Loader) postLoad(conf interface{}, docs []document, c codec, nilPtrs map[uintptr]bool) error {
+	if !isStructPtr(conf) {
+		return nil
+	}
+	if err := applyDefaults(reflect.ValueOf(conf).Elem(), "", false, nilPtrs); err != nil {
+		return err
+	}
+	if l.EnvOverride {
+		if err := l.ApplyEnv(conf); err != nil {
+			return err
+		}
+	}
+	if !l.Validate {
+		return nil
+	}
+	return validate(conf, docs, c.name)
+}
+
+// decodeTree assigns the merged tree of `docs` into the `conf` value.
+func (l *Loader) decodeTree(conf interface{}, docs []document, tree interface{}, c codec) error {
+	if tree == nil {
+		return nil
+	}
+	var (
+		b     []byte
+		lines [][]string
+		err   error
+	)
+	if c.encodeTree != nil {
+		b, lines = c.encodeTree(tree)
+	} else if b, err = c.enc.Encode(resolveTree(tree)); err != nil {
+		return fmt.Errorf("encode failed: %w", err)
+	}
+	if err := c.dec.Decode(b, conf); err != nil {
+		return fmt.Errorf("decode failed: %w", l.locateDecodeError(err, docs, tree, lines))
 	}
 	return nil
 }
 
-func readConfigBytes(data []byte, custom customFunc) ([]byte, error) {
+func readConfigBytes(data []byte, src source, custom customFunc) ([]byte, error) {
 	if custom == nil {
 		return data, nil
 	}
-	data, err := custom(data)
+	data, err := custom(data, src)
 	if err != nil {
-		// Go 1.12 text/template catches a panic raised in user-defined function.
-		// https://golang.org/doc/go1.12#text/template
-		if strings.Contains(err.Error(), "must_env: environment variable") {
-			panic(err)
-		}
 		return nil, err
 	}
 	return data, nil
@@ -167,65 +323,156 @@ var defaultLoader *Loader
 
 // Loader represents config loader.
 type Loader struct {
+	// Data is the data passed to templates. Use SetData to replace it while loading concurrently,
+	// or WithData to load with another data.
 	Data interface{}
 
-	mu         sync.Mutex
+	// FS is the file system to read config files from.
+	// If FS is nil, files are read from the OS file system.
+	FS fs.FS
+
+	// GlobMustMatch makes a glob pattern or a directory which matches no files an error.
+	GlobMustMatch bool
+
+	// EnvOverride enables to override fields of a loaded struct by environment variables
+	// named by `env` struct tags. See ApplyEnv.
+	EnvOverride bool
+
+	// EnvPrefix is the prefix of environment variable names in `env` struct tags.
+	EnvPrefix string
+
+	// Schema validates rendered and merged documents before decoding into the struct.
+	Schema *Schema
+
+	// Validate enables to validate loaded structs by `validate` struct tags and Validator.
+	// See the Validate function.
+	Validate bool
+
+	// LocalProfile is the profile applied after all active profiles by LoadWithEnvProfiles,
+	// if its overlay exists. If empty, DefaultLocalProfile is used. NoLocalProfile disables it.
+	LocalProfile string
+
+	// Strict makes keys in config files which don't match any field of the struct an error.
+	// All unknown keys in all files are reported in an *UnknownKeysError.
+	Strict bool
+
+	// Env is the source of environment variables for env and must_env in templates,
+	// ApplyEnv and ProfilesFromEnv. If Env is nil, the process environment is used.
+	Env Env
+
+	// Dotenv holds variables in addition to Env, without modifying the process environment.
+	// See LoadDotenv.
+	Dotenv map[string]string
+
+	// DotenvOverride makes Dotenv take precedence over Env.
+	DotenvOverride bool
+
+	// MustEnvPanic makes must_env panic with *MissingEnvsError instead of returning it,
+	// as older versions did.
+	MustEnvPanic bool
+
+	// MissingKey is the policy for references to missing keys of maps in Data.
+	// With MissingKeyFail, loading fails with *MissingKeyError.
+	MissingKey MissingKeyPolicy
+
+	mu sync.Mutex
+	settings
+}
+
+// settings holds unexported settings of Loader, which WithData copies in one assignment.
+// Maps and slices in settings are shared by copies, so they must be replaced, not modified.
+type settings struct {
 	leftDelim  string
 	rightDelim string
 	funcMap    template.FuncMap
+	strategies map[string]MergeStrategy
+	// strategyPaths holds keys of strategies in the order of registration.
+	strategyPaths []string
+	extensions    map[string]string
+	formats       map[string]codec
 }
 
 // DefaultFuncMap defines built-in template functions.
 var DefaultFuncMap = template.FuncMap{
-	"env": func(keys ...string) string {
-		v := ""
-		for _, k := range keys {
-			v = os.Getenv(k)
-			if v != "" {
-				return v
-			}
-			v = k
-		}
-		return v
-	},
-	"must_env": func(key string) string {
-		if v, ok := os.LookupEnv(key); ok {
-			return v
-		}
-		panic(fmt.Sprintf("environment variable %s is not defined", key))
-	},
+	"env":      env,
+	"must_env": mustEnv,
 	"json_escape": func(s string) string {
 		b, _ := json.Marshal(s)        // marshal as JSON string
 		return string(b[1 : len(b)-1]) // remove " on head and tail
 	},
+	"indent": indent,
+	"nindent": func(spaces int, s string) string {
+		return "\n" + indent(spaces, s)
+	},
 }
 
 // New creates a Loader instance.
 func New() *Loader {
-	l := &Loader{
-		funcMap: make(template.FuncMap, len(DefaultFuncMap)),
-	}
+	l := &Loader{}
 	l.Funcs(DefaultFuncMap)
 	return l
 }
 
-func (l *Loader) newTemplate() *template.Template {
+func (l *Loader) newTemplate(src source) *template.Template {
 	l.mu.Lock()
 	defer l.mu.Unlock()
-	tmpl := template.New("conf").Funcs(l.funcMap)
+	tmpl := template.New("conf").Funcs(template.FuncMap{
+		"include": l.includeFunc(src),
+	}).Funcs(l.funcMap).Option(l.MissingKey.option())
+	// built-in functions are replaced to look up Env and Dotenv
+	if sameFunc(l.funcMap["env"], env) {
+		tmpl.Funcs(template.FuncMap{"env": func(keys ...string) string {
+			return lookupEnvs(l.lookupEnv, keys)
+		}})
+	}
+	if sameFunc(l.funcMap["must_env"], mustEnv) {
+		tmpl.Funcs(template.FuncMap{"must_env": src.render.mustEnv(src, l.lookupEnv)})
+	}
+	if l.MissingKey == MissingKeyZero {
+		tmpl.Funcs(template.FuncMap{zeroNilFunc: zeroNil})
+	}
 	if l.leftDelim != "" && l.rightDelim != "" {
 		tmpl.Delims(l.leftDelim, l.rightDelim)
 	}
 	return tmpl
 }
 
-func (l *Loader) replacer(data []byte) ([]byte, error) {
-	t, err := l.newTemplate().Parse(string(data))
+func (l *Loader) replacer(data []byte, src source) ([]byte, error) {
+	if src.render != nil { // included
+		return l.render(data, src)
+	}
+	st := &renderState{}
+	src.render = st
+	b, err := l.render(data, src)
+	if st.missingKey != nil {
+		return nil, st.missingKey
+	}
+	if len(st.missing.Errors) > 0 {
+		err = &st.missing
+		if l.MustEnvPanic {
+			panic(err)
+		}
+	}
+	return b, err
+}
+
+func (l *Loader) render(data []byte, src source) ([]byte, error) {
+	t, err := l.newTemplate(src).Parse(string(data))
 	if err != nil {
 		return nil, fmt.Errorf("config parse by template failed: %w", err)
 	}
+	if l.MissingKey == MissingKeyZero {
+		for _, tt := range t.Templates() {
+			pipeZeroNil(tt.Tree, tt.Tree.Root)
+		}
+	}
 	buf := &bytes.Buffer{}
-	if err = t.Execute(buf, l.Data); err != nil {
+	err = t.Execute(buf, l.data())
+	src.render.locate(t, src, data)
+	if err != nil {
+		if e := missingKeyError(err, src); e != nil && src.render.missingKey == nil {
+			src.render.missingKey = e // the innermost include
+		}
 		return nil, fmt.Errorf("template attach failed: %w", err)
 	}
 	return buf.Bytes(), nil
@@ -234,66 +481,66 @@ func (l *Loader) replacer(data []byte) ([]byte, error) {
 // Load loads YAML files from `configPaths`.
 // and assigns decoded values into the `conf` value.
 func (l *Loader) Load(conf interface{}, configPaths ...string) error {
-	return loadWithFunc(conf, configPaths, nil, yaml.Unmarshal)
+	return l.loadAs("yaml", conf, configPaths, nil)
 }
 
 // LoadJSON loads JSON files from `configPaths`.
 // and assigns decoded values into the `conf` value.
 func (l *Loader) LoadJSON(conf interface{}, configPaths ...string) error {
-	return loadWithFunc(conf, configPaths, nil, json.Unmarshal)
+	return l.loadAs("json", conf, configPaths, nil)
 }
 
 // LoadTOML loads TOML files from `configPaths`.
 // and assigns decoded values into the `conf` value.
 func (l *Loader) LoadTOML(conf interface{}, configPaths ...string) error {
-	return loadWithFunc(conf, configPaths, nil, toml.Unmarshal)
+	return l.loadAs("toml", conf, configPaths, nil)
 }
 
 // LoadBytes loads YAML bytes
 func (l *Loader) LoadBytes(conf interface{}, src []byte) error {
-	return loadConfigBytes(conf, src, nil, yaml.Unmarshal)
+	return l.loadAsBytes("yaml", conf, src, nil)
 }
 
 // LoadJSONBytes loads JSON bytes
 func (l *Loader) LoadJSONBytes(conf interface{}, src []byte) error {
-	return loadConfigBytes(conf, src, nil, json.Unmarshal)
+	return l.loadAsBytes("json", conf, src, nil)
 }
 
 // LoadTOMLBytes loads TOML bytes
 func (l *Loader) LoadTOMLBytes(conf interface{}, src []byte) error {
-	return loadConfigBytes(conf, src, nil, toml.Unmarshal)
+	return l.loadAsBytes("toml", conf, src, nil)
 }
 
 // LoadWithEnv loads YAML files with Env
 // replace {{ env "ENV" }} to os.Getenv("ENV")
 // if you set default value then {{ env "ENV" "default" }}
 func (l *Loader) LoadWithEnv(conf interface{}, configPaths ...string) error {
-	return loadWithFunc(conf, configPaths, l.replacer, yaml.Unmarshal)
+	return l.loadAs("yaml", conf, configPaths, l.replacer)
 }
 
 // LoadWithEnvJSON loads JSON files with Env
 func (l *Loader) LoadWithEnvJSON(conf interface{}, configPaths ...string) error {
-	return loadWithFunc(conf, configPaths, l.replacer, json.Unmarshal)
+	return l.loadAs("json", conf, configPaths, l.replacer)
 }
 
 // LoadWithEnvTOML loads TOML files with Env
 func (l *Loader) LoadWithEnvTOML(conf interface{}, configPaths ...string) error {
-	return loadWithFunc(conf, configPaths, l.replacer, toml.Unmarshal)
+	return l.loadAs("toml", conf, configPaths, l.replacer)
 }
 
 // LoadWithEnvBytes loads YAML bytes with Env
 func (l *Loader) LoadWithEnvBytes(conf interface{}, src []byte) error {
-	return loadConfigBytes(conf, src, l.replacer, yaml.Unmarshal)
+	return l.loadAsBytes("yaml", conf, src, l.replacer)
 }
 
 // LoadWithEnvJSONBytes loads JSON bytes with Env
 func (l *Loader) LoadWithEnvJSONBytes(conf interface{}, src []byte) error {
-	return loadConfigBytes(conf, src, l.replacer, json.Unmarshal)
+	return l.loadAsBytes("json", conf, src, l.replacer)
 }
 
 // LoadWithEnvTOMLBytes loads TOML bytes with Env
 func (l *Loader) LoadWithEnvTOMLBytes(conf interface{}, src []byte) error {
-	return loadConfigBytes(conf, src, l.replacer, toml.Unmarshal)
+	return l.loadAsBytes("toml", conf, src, l.replacer)
 }
 
 // Delims sets the action delimiters to the specified strings.
@@ -308,19 +555,24 @@ func (l *Loader) Delims(left, right string) {
 func (l *Loader) Funcs(funcMap template.FuncMap) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
+	m := make(template.FuncMap, len(l.funcMap)+len(funcMap))
+	for name, fn := range l.funcMap {
+		m[name] = fn
+	}
 	for name, fn := range funcMap {
-		l.funcMap[name] = fn
+		m[name] = fn
 	}
+	l.funcMap = m
 }
 
 func (l *Loader) ReadWithEnv(configPath string) ([]byte, error) {
-	b, err := ioutil.ReadFile(configPath)
+	b, err := readFile(l.FS, configPath)
 	if err != nil {
 		return nil, err
 	}
-	return readConfigBytes(b, l.replacer)
+	return readConfigBytes(b, source{fsys: l.FS, name: configPath}, l.replacer)
 }
 
 func (l *Loader) ReadWithEnvBytes(b []byte) ([]byte, error) {
-	return readConfigBytes(b, l.replacer)
+	return readConfigBytes(b, source{fsys: l.FS}, l.replacer)
 }
@@ -1,11 +1,13 @@
 package config_test
 
 import (
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -17,12 +19,12 @@ var dir string
 type DBConfig struct {
 	Master  string        `yaml:"master"`
 	Slave   string        `yaml:"slave"`
-	Timeout time.Duration `yml:"timeout"`
+	Timeout time.Duration `yaml:"timeout"`
 }
 type Conf struct {
 	Domain  string        `yaml:"domain"`
 	IsDev   bool          `yaml:"is_dev"`
-	Timeout time.Duration `yml:"timeout"`
+	Timeout time.Duration `yaml:"timeout"`
 	DB      DBConfig      `yaml:"db"`
 }
 
@@ -43,12 +45,12 @@ func ExampleLoad() {
 	type DBConfig struct {
 		Master  string        `yaml:"master"`
 		Slave   string        `yaml:"slave"`
-		Timeout time.Duration `yml:"timeout"`
+		Timeout time.Duration `yaml:"timeout"`
 	}
 	type Conf struct {
 		Domain  string        `yaml:"domain"`
 		IsDev   bool          `yaml:"is_dev"`
-		Timeout time.Duration `yml:"timeout"`
+		Timeout time.Duration `yaml:"timeout"`
 		DB      DBConfig      `yaml:"db"`
 	}
 
@@ -83,12 +85,12 @@ func ExampleLoadWithEnv() {
 	type DBConfig struct {
 		Master  string        `yaml:"master"`
 		Slave   string        `yaml:"slave"`
-		Timeout time.Duration `yml:"timeout"`
+		Timeout time.Duration `yaml:"timeout"`
 	}
 	type Conf struct {
 		Domain  string        `yaml:"domain"`
 		IsDev   bool          `yaml:"is_dev"`
-		Timeout time.Duration `yml:"timeout"`
+		Timeout time.Duration `yaml:"timeout"`
 		DB      DBConfig      `yaml:"db"`
 	}
 
@@ -230,27 +232,97 @@ func genConfigFile(name string, config string) (string, error) {
 	return path, nil
 }
 
-func TestLoadMustEnvPanic(t *testing.T) {
-	f, err := genConfigFile("must-panic.yml", `## must.yml
-domain: '{{ must_env "MUST_DOMAIN_PANIC" }}'
+func TestLoadMustEnvMissing(t *testing.T) {
+	f, err := genConfigFile("must-missing.yml", `## must.yml
+domain: '{{ must_env "MUST_DOMAIN_MISSING" }}'
 `)
 	if err != nil {
 		t.Error(err)
 		return
 	}
-	defer func() {
-		if r := recover(); r == nil {
-			t.Error("must_env must raise panic")
-		} else {
-			t.Logf("must_env raise panic:%s", r)
-		}
-	}()
+	c := &Conf{}
+	err = config.LoadWithEnv(c, f)
+	var me *config.MissingEnvError
+	if !errors.As(err, &me) {
+		t.Fatalf("expected MissingEnvError, got %v", err)
+	}
+	expected := config.MissingEnvError{Name: "MUST_DOMAIN_MISSING", Source: f, Line: 2}
+	if *me != expected {
+		t.Errorf("unexpected error: %#v", me)
+	}
+	t.Log(err)
+
+	_, err = config.ReadWithEnv(f)
+	if !errors.As(err, &me) || *me != expected {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestLoadMustEnvMissingBytes(t *testing.T) {
+	src := []byte(`## must.yml
+domain: '{{ must_env "MUST_DOMAIN_MISSING" }}'
+`)
+	c := &Conf{}
+	err := config.LoadWithEnvBytes(c, src)
+	var me *config.MissingEnvError
+	if !errors.As(err, &me) {
+		t.Fatalf("expected MissingEnvError, got %v", err)
+	}
+	if expected := (config.MissingEnvError{Name: "MUST_DOMAIN_MISSING", Line: 2}); *me != expected {
+		t.Errorf("unexpected error: %#v", me)
+	}
+	if !strings.HasSuffix(err.Error(), "line 2: must_env: environment variable MUST_DOMAIN_MISSING is not defined") {
+		t.Errorf("unexpected message: %s", err)
+	}
+}
 
+func TestLoadMustEnvCollect(t *testing.T) {
+	base, err := genConfigFile("must-collect.yml", `## must.yml
+domain: '{{ must_env "MUST_COLLECT_DOMAIN" }}'
+db:
+  master: '{{ must_env "MUST_COLLECT_MASTER" }}'
+  slave: '{{ "MUST_COLLECT_MASTER" | must_env }}'
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	local, err := genConfigFile("must-collect.json", `{
+  "domain": "{{ must_env `+"`MUST_COLLECT_DOMAIN`"+` }}",
+  "is_dev": {{ must_env `+"`MUST_COLLECT_DEV`"+` | printf "%q" }}
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
 	c := &Conf{}
-	config.LoadWithEnv(c, f)
+	err = config.LoadWithEnvFiles(c, base, local)
+	var mes *config.MissingEnvsError
+	if !errors.As(err, &mes) {
+		t.Fatalf("expected MissingEnvsError, got %v", err)
+	}
+	expected := []config.MissingEnvError{
+		{Name: "MUST_COLLECT_DOMAIN", Source: base, Line: 2},
+		{Name: "MUST_COLLECT_MASTER", Source: base, Line: 4},
+		{Name: "MUST_COLLECT_DOMAIN", Source: local, Line: 2},
+		{Name: "MUST_COLLECT_DEV", Source: local, Line: 3},
+	}
+	if len(mes.Errors) != len(expected) {
+		t.Fatalf("unexpected errors: %s", err)
+	}
+	for i, e := range mes.Errors {
+		if *e != expected[i] {
+			t.Errorf("unexpected error[%d]: %#v", i, e)
+		}
+	}
+	t.Log(err)
+
+	var me *config.MissingEnvError
+	if !errors.As(err, &me) || *me != expected[0] {
+		t.Errorf("unexpected first error: %v", me)
+	}
 }
 
-func TestLoadMustEnvPanicBytes(t *testing.T) {
+func TestLoadMustEnvPanic(t *testing.T) {
 	src := []byte(`## must.yml
 domain: '{{ must_env "MUST_DOMAIN_PANIC" }}'
 `)
@@ -262,8 +334,10 @@ domain: '{{ must_env "MUST_DOMAIN_PANIC" }}'
 		}
 	}()
 
+	loader := config.New()
+	loader.MustEnvPanic = true
 	c := &Conf{}
-	err := config.LoadWithEnvBytes(c, src)
+	err := loader.LoadWithEnvBytes(c, src)
 	t.Log(err)
 }
 
@@ -0,0 +1,163 @@
+package config
+
+import (
+	"reflect"
+	"regexp"
+	"strconv"
+	"text/template/parse"
+)
+
+// MissingKeyPolicy represents how a template renders a missing key of a map in Loader.Data.
+type MissingKeyPolicy int
+
+const (
+	// MissingKeyDefault renders "<no value>", as text/template does by default.
+	MissingKeyDefault MissingKeyPolicy = iota
+	// MissingKeyZero renders the zero value of the map element,
+	// or "" if the element type is an interface (e.g. map[string]interface{}).
+	MissingKeyZero
+	// MissingKeyFail stops rendering with *MissingKeyError.
+	MissingKeyFail
+)
+
+// option returns the "missingkey" option of text/template.
+func (p MissingKeyPolicy) option() string {
+	switch p {
+	case MissingKeyZero:
+		return "missingkey=zero"
+	case MissingKeyFail:
+		return "missingkey=error"
+	}
+	return "missingkey=default"
+}
+
+// zeroNilFunc is the name of zeroNil in templates.
+const zeroNilFunc = "__config_zero_nil"
+
+// zeroNil renders nil as "", which text/template renders as "<no value>".
+func zeroNil(v interface{}) interface{} {
+	if v == nil {
+		return ""
+	}
+	return v
+}
+
+// pipeZeroNil appends zeroNil to the pipelines of actions which print values in `node`.
+// With missingkey=zero, a missing key of a map whose element type is an interface
+// is the nil interface, so it must be converted to be rendered as "".
+func pipeZeroNil(tree *parse.Tree, node parse.Node) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, c := range n.Nodes {
+			pipeZeroNil(tree, c)
+		}
+	case *parse.ActionNode:
+		if len(n.Pipe.Decl) > 0 { // assignments print nothing
+			return
+		}
+		ident := parse.NewIdentifier(zeroNilFunc).SetTree(tree).SetPos(n.Pos)
+		n.Pipe.Cmds = append(n.Pipe.Cmds, &parse.CommandNode{
+			NodeType: parse.NodeCommand,
+			Pos:      n.Pos,
+			Args:     []parse.Node{ident},
+		})
+	case *parse.IfNode:
+		pipeZeroNil(tree, n.List)
+		pipeZeroNil(tree, n.ElseList)
+	case *parse.RangeNode:
+		pipeZeroNil(tree, n.List)
+		pipeZeroNil(tree, n.ElseList)
+	case *parse.WithNode:
+		pipeZeroNil(tree, n.List)
+		pipeZeroNil(tree, n.ElseList)
+	}
+}
+
+// MissingKeyError is returned when a template refers to a missing key of a map in Loader.Data
+// with MissingKeyFail policy.
+type MissingKeyError struct {
+	Key    string // the missing key
+	Expr   string // the expression which refers to the key, e.g. ".db.host"
+	Source string // the file which has the template, empty for bytes and readers
+	Line   int    // line number in the template, 0 if unknown
+}
+
+func (e *MissingKeyError) Error() string {
+	prefix := ""
+	switch {
+	case e.Source != "" && e.Line > 0:
+		prefix = e.Source + ":" + strconv.Itoa(e.Line) + ": "
+	case e.Source != "":
+		prefix = e.Source + ": "
+	case e.Line > 0:
+		prefix = "line " + strconv.Itoa(e.Line) + ": "
+	}
+	msg := prefix + "missing key " + strconv.Quote(e.Key) + " in data"
+	if e.Expr != "" {
+		msg += " at <" + e.Expr + ">"
+	}
+	return msg
+}
+
+var (
+	templateLinePattern = regexp.MustCompile(`^template: [^:]*:(\d+):`)
+	missingKeyPattern   = regexp.MustCompile(`at <([^>]*)>: map has no entry for key "([^"]*)"`)
+)
+
+// missingKeyError returns *MissingKeyError for an execution error of text/template in `src`,
+// or nil if `err` is not caused by a missing key.
+func missingKeyError(err error, src source) *MissingKeyError {
+	m := missingKeyPattern.FindStringSubmatch(err.Error())
+	if m == nil {
+		return nil
+	}
+	e := &MissingKeyError{Key: m[2], Expr: m[1], Source: src.name}
+	if l := templateLinePattern.FindStringSubmatch(err.Error()); l != nil {
+		e.Line, _ = strconv.Atoi(l[1])
+	}
+	return e
+}
+
+// SetData replaces l.Data. It is safe to call while loading concurrently.
+func (l *Loader) SetData(data interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.Data = data
+}
+
+func (l *Loader) data() interface{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.Data
+}
+
+// WithData returns a copy of l which passes `data` to templates instead of l.Data.
+// The copy shares no mutable state with l, so loaders derived for each call can load concurrently.
+func (l *Loader) WithData(data interface{}) *Loader {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	c := &Loader{settings: l.settings}
+	// exported fields are copied by reflection not to miss fields added later,
+	// and maps (e.g. Dotenv) are copied not to be modified through the copy.
+	src, dst := reflect.ValueOf(l).Elem(), reflect.ValueOf(c).Elem()
+	for i := 0; i < src.NumField(); i++ {
+		if !src.Type().Field(i).IsExported() {
+			continue
+		}
+		f := src.Field(i)
+		if f.Kind() == reflect.Map && !f.IsNil() {
+			m := reflect.MakeMapWithSize(f.Type(), f.Len())
+			iter := f.MapRange()
+			for iter.Next() {
+				m.SetMapIndex(iter.Key(), iter.Value())
+			}
+			f = m
+		}
+		dst.Field(i).Set(f)
+	}
+	c.Data = data
+	return c
+}
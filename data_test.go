@@ -1,8 +1,15 @@
 package config_test
 
 import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
 	"testing"
+	"testing/fstest"
+	"text/template"
 
+	"github.com/google/go-cmp/cmp"
 	"github.com/kayac/go-config"
 )
 
@@ -21,3 +28,199 @@ func TestData(t *testing.T) {
 		t.Errorf("failed to inject foo: %#v", c)
 	}
 }
+
+func TestDataMissingKey(t *testing.T) {
+	src := []byte("foo: '{{ .foo }}'\nbar: '{{ .bar }}'\nbaz: '{{ if true }}{{ .baz }}{{ end }}'\nnum: '{{ .num }}'\nstr: '<no value>'\n")
+	tests := []struct {
+		policy   config.MissingKeyPolicy
+		data     interface{}
+		expected map[string]string
+	}{
+		{
+			config.MissingKeyDefault,
+			map[string]string{"foo": "DataFoo"},
+			map[string]string{"foo": "DataFoo", "bar": "<no value>", "baz": "<no value>", "num": "<no value>", "str": "<no value>"},
+		},
+		{
+			config.MissingKeyZero,
+			map[string]string{"foo": "DataFoo"},
+			map[string]string{"foo": "DataFoo", "bar": "", "baz": "", "num": "", "str": "<no value>"},
+		},
+		{
+			config.MissingKeyDefault,
+			map[string]interface{}{"foo": "DataFoo", "num": 0},
+			map[string]string{"foo": "DataFoo", "bar": "<no value>", "baz": "<no value>", "num": "0", "str": "<no value>"},
+		},
+		{
+			config.MissingKeyZero,
+			map[string]interface{}{"foo": "DataFoo", "num": 0},
+			map[string]string{"foo": "DataFoo", "bar": "", "baz": "", "num": "0", "str": "<no value>"},
+		},
+	}
+	for _, tt := range tests {
+		loader := config.New()
+		loader.Data = tt.data
+		loader.MissingKey = tt.policy
+		c := make(map[string]string)
+		if err := loader.LoadWithEnvBytes(&c, src); err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(tt.expected, c); diff != "" {
+			t.Errorf("policy %d with %T: unexpected config (-want +got):\n%s", tt.policy, tt.data, diff)
+		}
+	}
+
+	loader := config.New()
+	loader.Data = map[string]string{"foo": "DataFoo"}
+	loader.MissingKey = config.MissingKeyFail
+	c := make(map[string]string)
+	err := loader.LoadWithEnvBytes(&c, src)
+	var mk *config.MissingKeyError
+	if !errors.As(err, &mk) {
+		t.Fatalf("expected MissingKeyError, got %v", err)
+	}
+	expected := config.MissingKeyError{Key: "bar", Expr: ".bar", Line: 2}
+	if *mk != expected {
+		t.Errorf("unexpected error: %#v", mk)
+	}
+	if mk.Error() != `line 2: missing key "bar" in data at <.bar>` {
+		t.Errorf("unexpected message: %s", mk)
+	}
+}
+
+func TestDataMissingKeyInclude(t *testing.T) {
+	loader := config.New()
+	loader.FS = fstest.MapFS{
+		"conf/app.yaml":      {Data: []byte("domain: {{ .domain }}\ndb:{{ include \"parts/db.yaml\" | nindent 2 }}\n")},
+		"conf/parts/db.yaml": {Data: []byte("master: rw@/example\nslave: {{ .db.slave }}\n")},
+	}
+	loader.Data = map[string]interface{}{
+		"domain": "example.com",
+		"db":     map[string]string{"master": "rw@/example"},
+	}
+	loader.MissingKey = config.MissingKeyFail
+
+	c := &Conf{}
+	err := loader.LoadWithEnv(c, "conf/app.yaml")
+	var mk *config.MissingKeyError
+	if !errors.As(err, &mk) {
+		t.Fatalf("expected MissingKeyError, got %v", err)
+	}
+	expected := config.MissingKeyError{Key: "slave", Expr: ".db.slave", Source: "conf/parts/db.yaml", Line: 2}
+	if *mk != expected {
+		t.Errorf("unexpected error: %#v", mk)
+	}
+}
+
+func TestDataWithData(t *testing.T) {
+	loader := config.New()
+	loader.FS = fstest.MapFS{
+		"conf/app.yaml":      {Data: []byte("domain: <% .domain %>\ndb:<% include \"parts/db.yaml\" | nindent 2 %>\n")},
+		"conf/parts/db.yaml": {Data: []byte("master: <% .master %>\n")},
+	}
+	loader.Delims("<%", "%>")
+	loader.SetData(map[string]string{"domain": "base.example.com"})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		i := i
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			l := loader.WithData(map[string]string{
+				"domain": fmt.Sprintf("%d.example.com", i),
+				"master": fmt.Sprintf("rw@/%d", i),
+			})
+			for j := 0; j < 100; j++ {
+				c := &Conf{}
+				if err := l.LoadWithEnvBytes(c, []byte("domain: <% .domain %>\ndb:\n  master: <% .master %>\n")); err != nil {
+					t.Error(err)
+					return
+				}
+				expected := &Conf{Domain: fmt.Sprintf("%d.example.com", i), DB: DBConfig{Master: fmt.Sprintf("rw@/%d", i)}}
+				if diff := cmp.Diff(expected, c); diff != "" {
+					t.Errorf("unexpected config (-want +got):\n%s", diff)
+					return
+				}
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for j := 0; j < 100; j++ {
+			loader.SetData(map[string]string{"domain": "base.example.com"})
+		}
+	}()
+	wg.Wait()
+
+	c := &Conf{}
+	if err := loader.WithData(map[string]string{"domain": "derived.example.com", "master": "rw@/derived"}).LoadWithEnv(c, "conf/app.yaml"); err != nil {
+		t.Fatal(err)
+	}
+	if c.Domain != "derived.example.com" || c.DB.Master != "rw@/derived" {
+		t.Errorf("unexpected config: %#v", c)
+	}
+}
+
+func TestDataWithDataFields(t *testing.T) {
+	loader := config.New()
+	v := reflect.ValueOf(loader).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Type().Field(i)
+		if !f.IsExported() || f.Name == "Data" {
+			continue
+		}
+		var x reflect.Value
+		switch f.Type.Kind() {
+		case reflect.Bool:
+			x = reflect.ValueOf(true)
+		case reflect.String:
+			x = reflect.ValueOf("x")
+		case reflect.Int:
+			x = reflect.ValueOf(1)
+		case reflect.Map:
+			x = reflect.MakeMap(f.Type)
+			x.SetMapIndex(reflect.ValueOf("x"), reflect.ValueOf("y"))
+		case reflect.Ptr:
+			x = reflect.New(f.Type.Elem())
+		case reflect.Interface:
+			for _, c := range []interface{}{fstest.MapFS{}, config.MapEnv{}} {
+				if reflect.TypeOf(c).Implements(f.Type) {
+					x = reflect.ValueOf(c)
+				}
+			}
+		}
+		if !x.IsValid() {
+			t.Fatalf("no value for Loader.%s of %s", f.Name, f.Type)
+		}
+		v.Field(i).Set(x.Convert(f.Type))
+	}
+
+	c := loader.WithData("data")
+	cv := reflect.ValueOf(c).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Type().Field(i)
+		if !f.IsExported() || f.Name == "Data" {
+			continue
+		}
+		if !reflect.DeepEqual(v.Field(i).Interface(), cv.Field(i).Interface()) {
+			t.Errorf("Loader.%s is not copied: %v", f.Name, cv.Field(i).Interface())
+		}
+	}
+	if c.Data != "data" {
+		t.Errorf("unexpected data: %v", c.Data)
+	}
+
+	// settings of the copy are not shared with the loader
+	c.Dotenv["x"] = "z"
+	c.Funcs(template.FuncMap{"derived": func() string { return "derived" }})
+	if loader.Dotenv["x"] != "y" {
+		t.Errorf("Dotenv of the loader is modified: %v", loader.Dotenv)
+	}
+	loader.FS = nil
+	var conf map[string]string
+	if err := loader.LoadWithEnvBytes(&conf, []byte(`foo: '{{ derived }}'`)); err == nil {
+		t.Errorf("func registered to the copy is available in the loader: %v", conf)
+	}
+}
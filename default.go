@@ -0,0 +1,101 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// ApplyDefaults sets values in `default` struct tags into zero-valued fields of `conf`,
+// including fields of nested structs and of struct elements in slices and maps.
+// `conf` must be a pointer to a struct.
+//
+//	type Conf struct {
+//		Port    int           `yaml:"port" default:"8080"`
+//		Timeout time.Duration `yaml:"timeout" default:"30s"`
+//		Hosts   []string      `yaml:"hosts" default:"a.example.com,b.example.com"`
+//	}
+//
+// Loader applies defaults before decoding, so that values in config files win.
+// Elements of slices and maps, and structs behind pointers which are nil before decoding,
+// are decoded from config files, so that their defaults are applied after decoding,
+// only to fields which are still zero.
+func ApplyDefaults(conf interface{}) error {
+	if !isStructPtr(conf) {
+		return fmt.Errorf("ApplyDefaults requires a pointer to a struct, got %T", conf)
+	}
+	return applyDefaults(reflect.ValueOf(conf).Elem(), "", true, nil)
+}
+
+// applyDefaults sets defaults into zero-valued fields of the struct `v`.
+// If `set` is false, only fields in slice and map elements, and in structs behind pointers
+// in `nilPtrs` are set. If `set` is true and `nilPtrs` is not nil, the addresses of
+// nil pointers are recorded in `nilPtrs`.
+func applyDefaults(v reflect.Value, path string, set bool, nilPtrs map[uintptr]bool) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		fv := v.Field(i)
+		if f.PkgPath != "" && !f.Anonymous { // unexported
+			continue
+		}
+		fp := joinPath(path, f.Name)
+		if def, ok := f.Tag.Lookup("default"); ok && set && fv.IsZero() {
+			if err := setValue(fv, def); err != nil {
+				return fmt.Errorf("%s: default %q: %w", fp, def, err)
+			}
+		}
+		if err := applyDefaultsValue(fv, fp, set, nilPtrs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func applyDefaultsValue(v reflect.Value, path string, set bool, nilPtrs map[uintptr]bool) error {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			if set && nilPtrs != nil && v.CanAddr() {
+				nilPtrs[v.UnsafeAddr()] = true
+			}
+			return nil
+		}
+		if !set && v.CanAddr() && nilPtrs[v.UnsafeAddr()] { // allocated by decoding
+			return applyDefaultsValue(v.Elem(), path, true, nil)
+		}
+		return applyDefaultsValue(v.Elem(), path, set, nilPtrs)
+	case reflect.Struct:
+		if v.Type() == durationType {
+			return nil
+		}
+		return applyDefaults(v, path, set, nilPtrs)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := applyDefaultsValue(v.Index(i), fmt.Sprintf("%s[%d]", path, i), true, nil); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if indirectType(v.Type().Elem()).Kind() != reflect.Struct {
+			return nil
+		}
+		for _, k := range v.MapKeys() {
+			ep := joinPath(path, fmt.Sprint(k.Interface()))
+			e := v.MapIndex(k)
+			if e.Kind() == reflect.Ptr {
+				if err := applyDefaultsValue(e, ep, true, nil); err != nil {
+					return err
+				}
+				continue
+			}
+			// map elements are not addressable, so that a copy is set and stored back
+			c := reflect.New(e.Type()).Elem()
+			c.Set(e)
+			if err := applyDefaultsValue(c, ep, true, nil); err != nil {
+				return err
+			}
+			v.SetMapIndex(k, c)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package config_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/kayac/go-config"
+)
+
+type defaultServer struct {
+	Host   string `yaml:"host" json:"host" toml:"host"`
+	Port   int    `yaml:"port" json:"port" toml:"port" default:"80"`
+	Weight int    `yaml:"weight" json:"weight" toml:"weight" default:"1"`
+}
+
+type defaultConf struct {
+	Name    string          `yaml:"name" json:"name" toml:"name" default:"app"`
+	Debug   bool            `yaml:"debug" json:"debug" toml:"debug" default:"true"`
+	Timeout time.Duration   `yaml:"timeout" json:"timeout" toml:"timeout" default:"30s"`
+	Tags    []string        `yaml:"tags" json:"tags" toml:"tags" default:"a,b"`
+	DB      defaultServer   `yaml:"db" json:"db" toml:"db"`
+	Servers []defaultServer `yaml:"servers" json:"servers" toml:"servers"`
+}
+
+func TestDefaults(t *testing.T) {
+	expected := defaultConf{
+		Name:    "app",
+		Debug:   false,
+		Timeout: 30 * time.Second,
+		Tags:    []string{"c"},
+		DB:      defaultServer{Host: "db", Port: 3306, Weight: 1},
+		Servers: []defaultServer{
+			{Host: "s1", Port: 80, Weight: 1},
+			{Host: "s2", Port: 8080, Weight: 1},
+		},
+	}
+	tests := map[string]string{
+		"yaml": `
+debug: false
+tags: [c]
+db: {host: db, port: 3306}
+servers:
+  - host: s1
+  - {host: s2, port: 8080}
+`,
+		"json": `{
+  "debug": false,
+  "tags": ["c"],
+  "db": {"host": "db", "port": 3306},
+  "servers": [{"host": "s1"}, {"host": "s2", "port": 8080}]
+}`,
+		"toml": `
+debug = false
+tags = ["c"]
+[db]
+host = "db"
+port = 3306
+[[servers]]
+host = "s1"
+[[servers]]
+host = "s2"
+port = 8080
+`,
+	}
+	for format, src := range tests {
+		var c defaultConf
+		if err := config.LoadAsBytes(format, &c, []byte(src)); err != nil {
+			t.Errorf("%s: %s", format, err)
+			continue
+		}
+		if diff := cmp.Diff(expected, c); diff != "" {
+			t.Errorf("%s: unexpected config: %s", format, diff)
+		}
+	}
+}
+
+func TestApplyDefaults(t *testing.T) {
+	c := defaultConf{Name: "preset"}
+	if err := config.ApplyDefaults(&c); err != nil {
+		t.Fatal(err)
+	}
+	expected := defaultConf{
+		Name:    "preset",
+		Debug:   true,
+		Timeout: 30 * time.Second,
+		Tags:    []string{"a", "b"},
+		DB:      defaultServer{Port: 80, Weight: 1},
+	}
+	if diff := cmp.Diff(expected, c); diff != "" {
+		t.Errorf("unexpected config: %s", diff)
+	}
+}
+
+type defaultNestedConf struct {
+	DB       *defaultServer            `yaml:"db"`
+	Cache    *defaultServer            `yaml:"cache"`
+	Preset   *defaultServer            `yaml:"preset"`
+	Backends map[string]defaultServer  `yaml:"backends"`
+	Replicas map[string]*defaultServer `yaml:"replicas"`
+}
+
+func TestDefaultsNested(t *testing.T) {
+	src := []byte(`db:
+  host: db
+preset:
+  weight: 0
+backends:
+  a: {host: a, port: 8080}
+replicas:
+  r1: {host: r1}
+`)
+	c := defaultNestedConf{Preset: &defaultServer{}}
+	if err := config.LoadBytes(&c, src); err != nil {
+		t.Fatal(err)
+	}
+	expected := defaultNestedConf{
+		DB:       &defaultServer{Host: "db", Port: 80, Weight: 1},
+		Preset:   &defaultServer{Port: 80, Weight: 0}, // set before decoding, so that 0 in the file wins
+		Backends: map[string]defaultServer{"a": {Host: "a", Port: 8080, Weight: 1}},
+		Replicas: map[string]*defaultServer{"r1": {Host: "r1", Port: 80, Weight: 1}},
+	}
+	if diff := cmp.Diff(expected, c); diff != "" {
+		t.Errorf("unexpected config (-want +got):\n%s", diff)
+	}
+}
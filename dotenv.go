@@ -0,0 +1,161 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// LoadDotenv reads dotenv files into the Dotenv of the default loader.
+func LoadDotenv(paths ...string) error {
+	return defaultLoader.LoadDotenv(paths...)
+}
+
+// LoadDotenv reads dotenv files into l.Dotenv. Variables in later files take precedence.
+// See ParseDotenv for the syntax.
+func (l *Loader) LoadDotenv(paths ...string) error {
+	if l.Dotenv == nil {
+		l.Dotenv = make(map[string]string)
+	}
+	for _, p := range paths {
+		b, err := readFile(l.FS, p)
+		if err != nil {
+			return fmt.Errorf("%s read failed: %w", p, err)
+		}
+		vars, err := ParseDotenv(b, l.lookupEnv)
+		if err != nil {
+			return fmt.Errorf("%s parse failed: %w", p, err)
+		}
+		for k, v := range vars {
+			l.Dotenv[k] = v
+		}
+	}
+	return nil
+}
+
+var (
+	dotenvKey      = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+	dotenvVariable = regexp.MustCompile(`^\$(?:\{([A-Za-z_][A-Za-z0-9_.]*)\}|([A-Za-z_][A-Za-z0-9_]*))`)
+)
+
+// ParseDotenv parses a dotenv document.
+//
+//	# comment
+//	export KEY=value         # "export " is optional, and a comment after " #" is ignored
+//	KEY="line1\nline2 ${X}"  # escape sequences and expansion
+//	KEY='literal ${X}'       # no escape sequences nor expansion
+//	KEY="multi
+//	line"                    # quoted values can span lines
+//
+// `${VAR}` and `$VAR` in unquoted and double quoted values are expanded by variables
+// defined before them in the document, or `lookup` if not nil. Undefined variables are empty.
+func ParseDotenv(b []byte, lookup func(string) (string, bool)) (map[string]string, error) {
+	vars := make(map[string]string)
+	resolve := func(name string) string {
+		if v, ok := vars[name]; ok {
+			return v
+		}
+		if lookup != nil {
+			v, _ := lookup(name)
+			return v
+		}
+		return ""
+	}
+
+	lines := strings.Split(strings.Replace(string(b), "\r\n", "\n", -1), "\n")
+	for i := 0; i < len(lines); i++ {
+		lineNum := i + 1
+		line := strings.TrimSpace(lines[i])
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		eq := strings.Index(line, "=")
+		if eq < 0 {
+			return nil, fmt.Errorf("line %d: missing '='", lineNum)
+		}
+		key := strings.TrimSpace(line[:eq])
+		if !dotenvKey.MatchString(key) {
+			return nil, fmt.Errorf("line %d: invalid variable name %q", lineNum, key)
+		}
+		value := strings.TrimLeft(line[eq+1:], " \t")
+
+		if value == "" || (value[0] != '"' && value[0] != '\'') {
+			if c := strings.Index(value, " #"); c >= 0 {
+				value = value[:c]
+			}
+			vars[key] = expandDotenv(strings.TrimSpace(value), false, resolve)
+			continue
+		}
+
+		// quoted value, which may continue to following lines
+		quote := value[0]
+		rest := value[1:]
+		for {
+			if end := closingQuote(rest, quote); end >= 0 {
+				trailing := strings.TrimSpace(rest[end+1:])
+				if trailing != "" && !strings.HasPrefix(trailing, "#") {
+					return nil, fmt.Errorf("line %d: unexpected characters after the quoted value", i+1)
+				}
+				rest = rest[:end]
+				break
+			}
+			if i++; i >= len(lines) {
+				return nil, fmt.Errorf("line %d: unterminated quoted value", lineNum)
+			}
+			rest += "\n" + lines[i]
+		}
+		if quote == '\'' {
+			vars[key] = rest
+		} else {
+			vars[key] = expandDotenv(rest, true, resolve)
+		}
+	}
+	return vars, nil
+}
+
+// closingQuote returns the index of the closing `quote` in s, or -1.
+// Backslashes escape characters in double quotes.
+func closingQuote(s string, quote byte) int {
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && quote == '"':
+			i++
+		case s[i] == quote:
+			return i
+		}
+	}
+	return -1
+}
+
+// expandDotenv expands `${VAR}` and `$VAR` in s by `resolve`.
+// If escape is true, backslash escape sequences are also interpreted and `\$` is a literal "$".
+func expandDotenv(s string, escape bool, resolve func(string) string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\\' && escape && i+1 < len(s):
+			i++
+			switch s[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			default:
+				b.WriteByte(s[i])
+			}
+		case c == '$':
+			if m := dotenvVariable.FindStringSubmatch(s[i:]); m != nil {
+				b.WriteString(resolve(m[1] + m[2]))
+				i += len(m[0]) - 1
+				continue
+			}
+			b.WriteByte(c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
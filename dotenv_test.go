@@ -0,0 +1,103 @@
+package config_test
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/kayac/go-config"
+)
+
+func TestParseDotenv(t *testing.T) {
+	src := `# comment
+PLAIN=value
+export EXPORTED=exported  # inline comment
+SPACED = spaced value
+EMPTY=
+DOUBLE="double \"quoted\"\tvalue" # comment
+SINGLE='single ${PLAIN} \n'
+EXPANDED=${PLAIN}-$EXPORTED-${FROM_LOOKUP}-${UNDEFINED}
+ESCAPED="\${PLAIN} $$"
+MULTI="line1
+line2 ${PLAIN}"
+MULTI_SINGLE='a
+b'
+WINDOWS=crlf` + "\r\n"
+	lookup := func(key string) (string, bool) {
+		if key == "FROM_LOOKUP" {
+			return "looked up", true
+		}
+		return "", false
+	}
+	vars, err := config.ParseDotenv([]byte(src), lookup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		"PLAIN":        "value",
+		"EXPORTED":     "exported",
+		"SPACED":       "spaced value",
+		"EMPTY":        "",
+		"DOUBLE":       "double \"quoted\"\tvalue",
+		"SINGLE":       `single ${PLAIN} \n`,
+		"EXPANDED":     "value-exported-looked up-",
+		"ESCAPED":      "${PLAIN} $$",
+		"MULTI":        "line1\nline2 value",
+		"MULTI_SINGLE": "a\nb",
+		"WINDOWS":      "crlf",
+	}
+	if diff := cmp.Diff(expected, vars); diff != "" {
+		t.Errorf("unexpected vars (-want +got):\n%s", diff)
+	}
+}
+
+func TestParseDotenvError(t *testing.T) {
+	for _, src := range []string{
+		"NO_EQUAL\n",
+		"1INVALID=x\n",
+		"A=1\nUNTERMINATED=\"abc\n",
+		"TRAILING='abc' def\n",
+	} {
+		if _, err := config.ParseDotenv([]byte(src), nil); err == nil {
+			t.Errorf("expected error for %q", src)
+		} else {
+			t.Log(err)
+		}
+	}
+}
+
+func TestLoadDotenv(t *testing.T) {
+	t.Setenv("DOTENV_DOMAIN", "real.example.com")
+	fsys := fstest.MapFS{
+		".env":       {Data: []byte("DOTENV_DOMAIN=file.example.com\nDOTENV_MASTER=rw@/file\nDOTENV_SLAVE=ro@/file\n")},
+		".env.local": {Data: []byte("DOTENV_SLAVE=ro@/local-${DOTENV_DOMAIN}\n")},
+		"config.yml": {Data: []byte(`domain: {{ env "DOTENV_DOMAIN" }}
+db:
+  master: {{ must_env "DOTENV_MASTER" }}
+  slave: {{ env "DOTENV_SLAVE" "none" }}
+`)},
+	}
+	for _, override := range []bool{false, true} {
+		loader := config.New()
+		loader.FS = fsys
+		loader.DotenvOverride = override
+		if err := loader.LoadDotenv(".env", ".env.local"); err != nil {
+			t.Fatal(err)
+		}
+		var conf Conf
+		if err := loader.LoadWithEnv(&conf, "config.yml"); err != nil {
+			t.Fatal(err)
+		}
+		expected := Conf{
+			Domain: "real.example.com",
+			DB:     DBConfig{Master: "rw@/file", Slave: "ro@/local-real.example.com"},
+		}
+		if override {
+			expected.Domain = "file.example.com"
+			expected.DB.Slave = "ro@/local-file.example.com"
+		}
+		if diff := cmp.Diff(expected, conf); diff != "" {
+			t.Errorf("override=%v unexpected config (-want +got):\n%s", override, diff)
+		}
+	}
+}
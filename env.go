@@ -0,0 +1,225 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// MissingEnvError is returned when an environment variable required by must_env is not defined.
+type MissingEnvError struct {
+	Name   string // name of the environment variable
+	Source string // the file which requires the variable, empty for bytes and readers
+	Line   int    // line number in the template, 0 if unknown
+}
+
+func (e *MissingEnvError) Error() string {
+	prefix := ""
+	switch {
+	case e.Source != "" && e.Line > 0:
+		prefix = e.Source + ":" + strconv.Itoa(e.Line) + ": "
+	case e.Source != "":
+		prefix = e.Source + ": "
+	case e.Line > 0:
+		prefix = "line " + strconv.Itoa(e.Line) + ": "
+	}
+	return prefix + "must_env: environment variable " + e.Name + " is not defined"
+}
+
+// Env is a source of environment variables.
+type Env interface {
+	LookupEnv(key string) (string, bool)
+}
+
+// OSEnv is the environment of the process.
+var OSEnv Env = EnvFunc(os.LookupEnv)
+
+// EnvFunc is an adapter to allow the use of ordinary functions as Env.
+type EnvFunc func(key string) (string, bool)
+
+// LookupEnv calls f(key).
+func (f EnvFunc) LookupEnv(key string) (string, bool) {
+	return f(key)
+}
+
+// MapEnv is an Env of variables in a map.
+type MapEnv map[string]string
+
+// LookupEnv returns m[key].
+func (m MapEnv) LookupEnv(key string) (string, bool) {
+	v, ok := m[key]
+	return v, ok
+}
+
+// LayeredEnv looks up Envs in order, and returns the first defined variable.
+type LayeredEnv []Env
+
+// LookupEnv looks up `key` in the Envs in order.
+func (e LayeredEnv) LookupEnv(key string) (string, bool) {
+	for _, env := range e {
+		if env == nil {
+			continue
+		}
+		if v, ok := env.LookupEnv(key); ok {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// PrefixedEnv looks up variables in Env with the names prefixed by Prefix.
+// e.g. "DB_HOST" is looked up as "APP_DB_HOST" by the Prefix "APP_".
+type PrefixedEnv struct {
+	Prefix string
+	Env    Env // OSEnv if nil
+}
+
+// LookupEnv looks up Prefix + key.
+func (e PrefixedEnv) LookupEnv(key string) (string, bool) {
+	env := e.Env
+	if env == nil {
+		env = OSEnv
+	}
+	return env.LookupEnv(e.Prefix + key)
+}
+
+// lookupEnv looks up `key` in l.Env (OSEnv if nil) and l.Dotenv.
+func (l *Loader) lookupEnv(key string) (string, bool) {
+	env := l.Env
+	if env == nil {
+		env = OSEnv
+	}
+	if l.DotenvOverride {
+		return LayeredEnv{MapEnv(l.Dotenv), env}.LookupEnv(key)
+	}
+	return LayeredEnv{env, MapEnv(l.Dotenv)}.LookupEnv(key)
+}
+
+// env is the built-in env template function.
+// It returns the value of the first non-empty variable in keys,
+// or the last key as the default value.
+func env(keys ...string) string {
+	return lookupEnvs(os.LookupEnv, keys)
+}
+
+func lookupEnvs(lookup func(string) (string, bool), keys []string) string {
+	v := ""
+	for _, k := range keys {
+		v, _ = lookup(k)
+		if v != "" {
+			return v
+		}
+		v = k
+	}
+	return v
+}
+
+// mustEnv is the built-in must_env template function.
+// Loader replaces it by renderState.mustEnv to know where it is called.
+func mustEnv(key string) (string, error) {
+	return lookupMustEnv(os.LookupEnv, key)
+}
+
+func lookupMustEnv(lookup func(string) (string, bool), key string) (string, error) {
+	if v, ok := lookup(key); ok {
+		return v, nil
+	}
+	return "", &MissingEnvError{Name: key}
+}
+
+// MissingEnvsError is returned when environment variables required by must_env are not defined.
+// It lists all of them in all files, and errors.As finds its first *MissingEnvError.
+type MissingEnvsError struct {
+	Errors []*MissingEnvError
+}
+
+func (e *MissingEnvsError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, me := range e.Errors {
+		msgs = append(msgs, me.Error())
+	}
+	return "missing environment variables:\n  " + strings.Join(msgs, "\n  ")
+}
+
+// As finds the first *MissingEnvError for errors.As.
+func (e *MissingEnvsError) As(target interface{}) bool {
+	if t, ok := target.(**MissingEnvError); ok && len(e.Errors) > 0 {
+		*t = e.Errors[0]
+		return true
+	}
+	return false
+}
+
+// add adds errors in `err` if it is a *MissingEnvsError, and reports whether it is.
+func (e *MissingEnvsError) add(err error) bool {
+	var mes *MissingEnvsError
+	if !errors.As(err, &mes) {
+		return false
+	}
+	for _, me := range mes.Errors {
+		if !e.has(me) {
+			e.Errors = append(e.Errors, me)
+		}
+	}
+	return true
+}
+
+func (e *MissingEnvsError) has(me *MissingEnvError) bool {
+	for _, x := range e.Errors {
+		if *x == *me {
+			return true
+		}
+	}
+	return false
+}
+
+// renderState is shared by a template and templates included by it.
+type renderState struct {
+	missing    MissingEnvsError
+	missingKey *MissingKeyError
+}
+
+// mustEnv returns must_env for `src`, which records undefined variables and renders them as
+// empty strings, to report all of them after rendering.
+func (st *renderState) mustEnv(src source, lookup func(string) (string, bool)) func(string) string {
+	return func(key string) string {
+		v, err := lookupMustEnv(lookup, key)
+		if err != nil {
+			e := err.(*MissingEnvError)
+			e.Source = src.name
+			if !st.missing.has(e) {
+				st.missing.Errors = append(st.missing.Errors, e)
+			}
+		}
+		return v
+	}
+}
+
+// locate fills line numbers of variables required by `src` by static calls of must_env in `t`.
+func (st *renderState) locate(t *template.Template, src source, data []byte) {
+	w := &refWalker{data: data, src: src.name}
+	for _, tt := range t.Templates() {
+		if tt.Tree != nil {
+			w.node(tt.Tree.Root)
+		}
+	}
+	for _, e := range st.missing.Errors {
+		if e.Source != src.name || e.Line != 0 {
+			continue
+		}
+		for _, ref := range w.refs {
+			if ref.Func == "must_env" && len(ref.Keys) == 1 && ref.Keys[0] == e.Name {
+				e.Line = ref.Line
+				break
+			}
+		}
+	}
+}
+
+func sameFunc(a, b interface{}) bool {
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	return va.Kind() == reflect.Func && vb.Kind() == reflect.Func && va.Pointer() == vb.Pointer()
+}
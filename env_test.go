@@ -0,0 +1,101 @@
+package config_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/kayac/go-config"
+)
+
+func TestEnvSources(t *testing.T) {
+	t.Setenv("ENV_SOURCE_OS", "os")
+	t.Setenv("APP_ENV_SOURCE_OS", "prefixed os")
+	base := config.MapEnv{"A": "base a", "B": "base b"}
+	overlay := config.MapEnv{"A": "overlay a", "EMPTY": ""}
+	env := config.LayeredEnv{
+		overlay,
+		base,
+		config.PrefixedEnv{Prefix: "APP_"},
+		nil,
+		config.EnvFunc(func(key string) (string, bool) { return "func " + key, key == "FUNC" }),
+	}
+	tests := []struct {
+		key   string
+		value string
+		ok    bool
+	}{
+		{"A", "overlay a", true},
+		{"B", "base b", true},
+		{"EMPTY", "", true},
+		{"ENV_SOURCE_OS", "prefixed os", true},
+		{"FUNC", "func FUNC", true},
+		{"UNDEFINED", "", false},
+	}
+	for _, tt := range tests {
+		v, ok := env.LookupEnv(tt.key)
+		if v != tt.value || ok != tt.ok {
+			t.Errorf("LookupEnv(%q) = %q, %v; want %q, %v", tt.key, v, ok, tt.value, tt.ok)
+		}
+	}
+	if v, _ := config.OSEnv.LookupEnv("ENV_SOURCE_OS"); v != "os" {
+		t.Errorf("unexpected OSEnv value: %q", v)
+	}
+}
+
+func TestLoaderEnv(t *testing.T) {
+	src := []byte(`domain: {{ env "DOMAIN" "default.example.com" }}
+db:
+  master: {{ must_env "MASTER" }}
+`)
+	for i := 0; i < 4; i++ {
+		i := i
+		t.Run(fmt.Sprint(i), func(t *testing.T) {
+			t.Parallel()
+			loader := config.New()
+			loader.Env = config.MapEnv{
+				"DOMAIN": fmt.Sprintf("%d.example.com", i),
+				"MASTER": fmt.Sprintf("rw@/%d", i),
+			}
+			loader.Dotenv = map[string]string{"DOMAIN": "dotenv.example.com", "NAME": "dotenv"}
+			for j := 0; j < 100; j++ {
+				var conf Conf
+				if err := loader.LoadWithEnvBytes(&conf, src); err != nil {
+					t.Fatal(err)
+				}
+				expected := Conf{
+					Domain: fmt.Sprintf("%d.example.com", i),
+					DB:     DBConfig{Master: fmt.Sprintf("rw@/%d", i)},
+				}
+				if diff := cmp.Diff(expected, conf); diff != "" {
+					t.Fatalf("unexpected config (-want +got):\n%s", diff)
+				}
+			}
+		})
+	}
+}
+
+func TestLoaderEnvApplyEnv(t *testing.T) {
+	loader := config.New()
+	loader.Env = config.PrefixedEnv{Prefix: "APP_", Env: config.MapEnv{
+		"APP_NAME":    "from_map",
+		"APP_DB_PORT": "3307",
+		"APP_PROFILE": "production, canary",
+	}}
+	loader.Dotenv = map[string]string{"DB_HOST": "dotenv.example.com"}
+
+	var conf envConf
+	if err := loader.ApplyEnv(&conf); err != nil {
+		t.Fatal(err)
+	}
+	expected := envConf{
+		Name: "from_map",
+		DB:   envDBConfig{Host: "dotenv.example.com", Port: 3307},
+	}
+	if diff := cmp.Diff(expected, conf); diff != "" {
+		t.Errorf("unexpected config (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"production", "canary"}, loader.ProfilesFromEnv("PROFILE")); diff != "" {
+		t.Errorf("unexpected profiles (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,209 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"text/template/parse"
+)
+
+// EnvRef is a call of env or must_env in a config template.
+type EnvRef struct {
+	Func string // "env" or "must_env"
+
+	// Keys are the names of environment variables in the order of precedence.
+	// Keys is empty if the call has non-constant arguments.
+	Keys []string
+
+	Default    string // the last argument of env with two or more arguments
+	HasDefault bool
+	Dynamic    bool // the call has non-constant arguments
+
+	Source string // the file which has the call, empty for bytes
+	Line   int
+	Column int // byte offset in the line, starting at 1
+}
+
+func (r EnvRef) String() string {
+	s := fmt.Sprintf("%s:%d:%d: %s", r.Source, r.Line, r.Column, r.Func)
+	for _, k := range r.Keys {
+		s += " " + k
+	}
+	if r.Dynamic {
+		s += " (dynamic)"
+	}
+	if r.HasDefault {
+		s += fmt.Sprintf(" (default %q)", r.Default)
+	}
+	return s
+}
+
+// EnvRefs returns calls of env and must_env in templates of `configPaths`, without executing them.
+func EnvRefs(configPaths ...string) ([]EnvRef, error) {
+	return defaultLoader.EnvRefs(configPaths...)
+}
+
+// EnvRefsBytes returns calls of env and must_env in the template `b`, without executing it.
+func EnvRefsBytes(b []byte) ([]EnvRef, error) {
+	return defaultLoader.EnvRefsBytes(b)
+}
+
+// EnvRefs returns calls of env and must_env in templates of `configPaths` and files included by them,
+// without executing them. Templates are parsed with the delimiters and functions of the Loader.
+// Files included by non-constant paths are not analyzed.
+func (l *Loader) EnvRefs(configPaths ...string) ([]EnvRef, error) {
+	var refs []EnvRef
+	for _, p := range configPaths {
+		r, err := l.envRefs(source{fsys: l.FS, name: p})
+		if err != nil {
+			return nil, err
+		}
+		refs = append(refs, r...)
+	}
+	return refs, nil
+}
+
+// EnvRefsBytes returns calls of env and must_env in the template `b`, without executing it.
+func (l *Loader) EnvRefsBytes(b []byte) ([]EnvRef, error) {
+	return l.envRefsBytes(b, source{fsys: l.FS})
+}
+
+func (l *Loader) envRefs(src source) ([]EnvRef, error) {
+	data, err := readFile(src.fsys, src.name)
+	if err != nil {
+		return nil, fmt.Errorf("%s read failed: %w", src.name, err)
+	}
+	return l.envRefsBytes(data, src)
+}
+
+func (l *Loader) envRefsBytes(data []byte, src source) ([]EnvRef, error) {
+	t, err := l.newTemplate(src).Parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s config parse by template failed: %w", src.name, err)
+	}
+	w := &refWalker{data: data, src: src.name}
+	for _, tt := range t.Templates() {
+		if tt.Tree != nil {
+			w.node(tt.Tree.Root)
+		}
+	}
+	refs := w.refs
+	stack := append(src.includedBy[:len(src.includedBy):len(src.includedBy)], src.name)
+	for _, name := range w.includes {
+		p := resolvePath(src, name)
+		if containsString(stack, p) {
+			continue // cycle
+		}
+		r, err := l.envRefs(source{fsys: src.fsys, name: p, includedBy: stack})
+		if err != nil {
+			return nil, err
+		}
+		refs = append(refs, r...)
+	}
+	return refs, nil
+}
+
+// refWalker walks a parse tree of a template to find env, must_env and include calls.
+type refWalker struct {
+	data     []byte
+	src      string
+	refs     []EnvRef
+	includes []string // constant paths of include
+}
+
+func (w *refWalker) node(node parse.Node) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, c := range n.Nodes {
+			w.node(c)
+		}
+	case *parse.ActionNode:
+		w.pipe(n.Pipe)
+	case *parse.IfNode:
+		w.branch(&n.BranchNode)
+	case *parse.RangeNode:
+		w.branch(&n.BranchNode)
+	case *parse.WithNode:
+		w.branch(&n.BranchNode)
+	case *parse.TemplateNode:
+		w.pipe(n.Pipe)
+	}
+}
+
+func (w *refWalker) branch(n *parse.BranchNode) {
+	w.pipe(n.Pipe)
+	w.node(n.List)
+	w.node(n.ElseList)
+}
+
+func (w *refWalker) pipe(n *parse.PipeNode) {
+	if n == nil {
+		return
+	}
+	for i, cmd := range n.Cmds {
+		var piped parse.Node // the result of the previous command, e.g. {{ "NAME" | must_env }}
+		if i > 0 && len(n.Cmds[i-1].Args) == 1 {
+			piped = n.Cmds[i-1].Args[0]
+		}
+		w.command(cmd, i > 0, piped)
+	}
+}
+
+func (w *refWalker) command(cmd *parse.CommandNode, isPiped bool, piped parse.Node) {
+	for _, arg := range cmd.Args {
+		if p, ok := arg.(*parse.PipeNode); ok {
+			w.pipe(p)
+		}
+	}
+	id, ok := cmd.Args[0].(*parse.IdentifierNode)
+	if !ok {
+		return
+	}
+	args := cmd.Args[1:]
+	if isPiped {
+		args = append(args[:len(args):len(args)], piped)
+	}
+	var keys []string
+	dynamic := false
+	for _, arg := range args {
+		s, ok := arg.(*parse.StringNode)
+		if !ok {
+			keys, dynamic = nil, true
+			break
+		}
+		keys = append(keys, s.Text)
+	}
+	switch id.Ident {
+	case "env", "must_env":
+		ref := EnvRef{Func: id.Ident, Source: w.src, Dynamic: dynamic}
+		ref.Line, ref.Column = w.position(id.Position())
+		if id.Ident == "env" && len(keys) > 1 {
+			ref.Keys, ref.Default, ref.HasDefault = keys[:len(keys)-1], keys[len(keys)-1], true
+		} else {
+			ref.Keys = keys
+		}
+		w.refs = append(w.refs, ref)
+	case "include":
+		if len(keys) == 1 {
+			w.includes = append(w.includes, keys[0])
+		}
+	}
+}
+
+func (w *refWalker) position(pos parse.Pos) (line, column int) {
+	b := w.data[:int(pos)]
+	line = bytes.Count(b, []byte("\n")) + 1
+	column = len(b) - bytes.LastIndexByte(b, '\n')
+	return line, column
+}
+
+func containsString(ss []string, s string) bool {
+	for _, x := range ss {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
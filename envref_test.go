@@ -0,0 +1,62 @@
+package config_test
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/kayac/go-config"
+)
+
+var envRefFS = fstest.MapFS{
+	"app.yaml": {Data: []byte(`domain: {{ env "APP_DOMAIN" "APP_HOST" "example.com" }}
+db:
+  master: {{ must_env "DB_MASTER" }}
+  slave: {{ "DB_SLAVE" | must_env }}
+{{ if .debug }}  debug: {{ env .debug_env }}{{ end }}
+{{ include "parts/redis.yaml" }}
+{{ include .dynamic }}
+`)},
+	"parts/redis.yaml": {Data: []byte(`redis: {{ env "REDIS_URL" | printf "%q" }}
+{{ include "../app.yaml" }}`)},
+	"delims.yaml": {Data: []byte(`domain: <% env "APP_DOMAIN" %>`)},
+}
+
+func TestEnvRefs(t *testing.T) {
+	loader := config.New()
+	loader.FS = envRefFS
+	refs, err := loader.EnvRefs("app.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []config.EnvRef{
+		{Func: "env", Keys: []string{"APP_DOMAIN", "APP_HOST"}, Default: "example.com", HasDefault: true, Source: "app.yaml", Line: 1, Column: 12},
+		{Func: "must_env", Keys: []string{"DB_MASTER"}, Source: "app.yaml", Line: 3, Column: 14},
+		{Func: "must_env", Keys: []string{"DB_SLAVE"}, Source: "app.yaml", Line: 4, Column: 26},
+		{Func: "env", Dynamic: true, Source: "app.yaml", Line: 5, Column: 28},
+		{Func: "env", Keys: []string{"REDIS_URL"}, Source: "parts/redis.yaml", Line: 1, Column: 11},
+	}
+	if diff := cmp.Diff(expected, refs); diff != "" {
+		t.Errorf("unexpected refs (-want +got):\n%s", diff)
+	}
+}
+
+func TestEnvRefsDelims(t *testing.T) {
+	loader := config.New()
+	loader.FS = envRefFS
+	loader.Delims("<%", "%>")
+	refs, err := loader.EnvRefs("delims.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []config.EnvRef{
+		{Func: "env", Keys: []string{"APP_DOMAIN"}, Source: "delims.yaml", Line: 1, Column: 12},
+	}
+	if diff := cmp.Diff(expected, refs); diff != "" {
+		t.Errorf("unexpected refs (-want +got):\n%s", diff)
+	}
+
+	if _, err := loader.EnvRefsBytes([]byte(`<% unknown_func "X" %>`)); err == nil {
+		t.Error("expected error for an undefined function")
+	}
+}
@@ -0,0 +1,78 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// ApplyEnv overrides fields of `conf` by environment variables named by `env` struct tags.
+func ApplyEnv(conf interface{}) error {
+	return defaultLoader.ApplyEnv(conf)
+}
+
+// ApplyEnv overrides fields of `conf` by environment variables named by `env` struct tags.
+// `conf` must be a pointer to a struct. Variables are looked up in l.Env and l.Dotenv.
+//
+// Fields are set only if the variable is defined. Slices are split by comma,
+// and time.Duration and encoding.TextUnmarshaler are supported.
+// The names are prefixed by l.EnvPrefix, and an `envPrefix` tag on a nested struct field
+// adds a prefix to the names in the nested struct.
+//
+//	type DBConfig struct {
+//		Host string `env:"HOST"`
+//	}
+//	type Conf struct {
+//		Hosts []string `env:"HOSTS"`
+//		DB    DBConfig `envPrefix:"DB_"` // DB_HOST
+//	}
+func (l *Loader) ApplyEnv(conf interface{}) error {
+	if !isStructPtr(conf) {
+		return fmt.Errorf("ApplyEnv requires a pointer to a struct, got %T", conf)
+	}
+	return applyEnv(reflect.ValueOf(conf).Elem(), l.lookupEnv, l.EnvPrefix, "")
+}
+
+func isStructPtr(v interface{}) bool {
+	rv := reflect.ValueOf(v)
+	return rv.Kind() == reflect.Ptr && !rv.IsNil() && rv.Elem().Kind() == reflect.Struct
+}
+
+func applyEnv(v reflect.Value, lookup func(string) (string, bool), prefix, path string) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		fv := v.Field(i)
+		if f.PkgPath != "" && !f.Anonymous { // unexported
+			continue
+		}
+		fp := joinPath(path, f.Name)
+		if key, ok := f.Tag.Lookup("env"); ok && key != "-" {
+			name := prefix + key
+			if s, ok := lookup(name); ok {
+				if err := setValue(fv, s); err != nil {
+					return fmt.Errorf("%s: env %s: %w", fp, name, err)
+				}
+			}
+			continue
+		}
+		if ft := indirectType(f.Type); ft.Kind() == reflect.Struct && ft != durationType {
+			if fv.Kind() == reflect.Ptr {
+				if fv.IsNil() {
+					continue
+				}
+				fv = fv.Elem()
+			}
+			if err := applyEnv(fv, lookup, prefix+f.Tag.Get("envPrefix"), fp); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
@@ -0,0 +1,84 @@
+package config_test
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/kayac/go-config"
+)
+
+type envDBConfig struct {
+	Host    string        `yaml:"host" env:"HOST"`
+	Port    int           `yaml:"port" env:"PORT"`
+	Timeout time.Duration `yaml:"timeout" env:"TIMEOUT"`
+}
+
+type envConf struct {
+	Name    string       `yaml:"name" env:"NAME"`
+	Debug   bool         `yaml:"debug" env:"DEBUG"`
+	Hosts   []string     `yaml:"hosts" env:"HOSTS"`
+	Ports   []int        `yaml:"ports" env:"PORTS"`
+	Addr    net.IP       `yaml:"addr" env:"ADDR"`
+	Ratio   *float64     `yaml:"ratio" env:"RATIO"`
+	DB      envDBConfig  `yaml:"db" envPrefix:"DB_"`
+	Replica *envDBConfig `yaml:"replica" envPrefix:"REPLICA_"`
+	Ignored string       `yaml:"ignored"`
+}
+
+func TestEnvOverride(t *testing.T) {
+	t.Setenv("APP_NAME", "from_env")
+	t.Setenv("APP_HOSTS", "a.example.com, b.example.com")
+	t.Setenv("APP_PORTS", "80,443")
+	t.Setenv("APP_ADDR", "192.0.2.1")
+	t.Setenv("APP_RATIO", "0.5")
+	t.Setenv("APP_DB_PORT", "3307")
+	t.Setenv("APP_DB_TIMEOUT", "3s")
+	t.Setenv("APP_REPLICA_HOST", "replica.example.com")
+
+	loader := config.New()
+	loader.EnvOverride = true
+	loader.EnvPrefix = "APP_"
+
+	var c envConf
+	err := loader.LoadWithEnvBytes(&c, []byte(`
+name: from_file
+debug: true
+db:
+  host: db.example.com
+  port: 3306
+replica:
+  host: replica.local
+ignored: file
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ratio := 0.5
+	expected := envConf{
+		Name:  "from_env",
+		Debug: true,
+		Hosts: []string{"a.example.com", "b.example.com"},
+		Ports: []int{80, 443},
+		Addr:  net.ParseIP("192.0.2.1"),
+		Ratio: &ratio,
+		DB: envDBConfig{
+			Host:    "db.example.com",
+			Port:    3307,
+			Timeout: 3 * time.Second,
+		},
+		Replica: &envDBConfig{Host: "replica.example.com"},
+		Ignored: "file",
+	}
+	if diff := cmp.Diff(expected, c); diff != "" {
+		t.Errorf("unexpected config: %s", diff)
+	}
+
+	t.Setenv("APP_DB_PORT", "not a number")
+	if err := loader.ApplyEnv(&c); err == nil {
+		t.Error("invalid number must be an error")
+	} else {
+		t.Log(err)
+	}
+}
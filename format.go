@@ -0,0 +1,259 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+)
+
+// TemplateExt is the extension stripped from a path before detecting its format.
+// e.g. "app.json.tmpl" is loaded as JSON.
+const TemplateExt = ".tmpl"
+
+// Decoder decodes a config document.
+// When v is a *interface{}, Decode must store generic values
+// (maps, slices and scalars) which can be encoded by the Encoder of the same format.
+type Decoder interface {
+	Decode(data []byte, v interface{}) error
+}
+
+// Encoder encodes a value into a config document.
+type Encoder interface {
+	Encode(v interface{}) ([]byte, error)
+}
+
+// DecoderFunc is an adapter to allow the use of ordinary functions as Decoder.
+type DecoderFunc func(data []byte, v interface{}) error
+
+// Decode calls f(data, v).
+func (f DecoderFunc) Decode(data []byte, v interface{}) error {
+	return f(data, v)
+}
+
+// EncoderFunc is an adapter to allow the use of ordinary functions as Encoder.
+type EncoderFunc func(v interface{}) ([]byte, error)
+
+// Encode calls f(v).
+func (f EncoderFunc) Encode(v interface{}) ([]byte, error) {
+	return f(v)
+}
+
+// codec is a pair of Decoder and Encoder of a config format.
+type codec struct {
+	name string // also used as the struct tag name of the format
+	dec  Decoder
+	enc  Encoder
+
+	// decodeTree and encodeTree convert a document to a tree and back, instead of dec and enc
+	// with generic values. encodeTree also returns the keys of the value on each line.
+	decodeTree func(data []byte) (interface{}, error)
+	encodeTree func(tree interface{}) ([]byte, [][]string)
+
+	// unknownKeys finds keys in a rendered document which don't match the struct type.
+	// If nil, the decoded tree is checked without line numbers.
+	unknownKeys func(data []byte, t reflect.Type) []UnknownKey
+}
+
+var builtinCodecs = map[string]codec{
+	"yaml": {
+		name: "yaml", dec: DecoderFunc(yaml.Unmarshal), enc: EncoderFunc(yaml.Marshal),
+		decodeTree: decodeYAMLTree, encodeTree: encodeYAMLTree, unknownKeys: yamlUnknownKeys,
+	},
+	"json": {name: "json", dec: DecoderFunc(unmarshalJSON), enc: EncoderFunc(json.Marshal), unknownKeys: jsonUnknownKeys},
+	"toml": {name: "toml", dec: DecoderFunc(toml.Unmarshal), enc: EncoderFunc(marshalTOML), unknownKeys: tomlUnknownKeys},
+}
+
+var defaultExtensions = map[string]string{
+	".yaml": "yaml",
+	".yml":  "yaml",
+	".json": "json",
+	".toml": "toml",
+}
+
+// unmarshalJSON is json.Unmarshal but keeps numbers as json.Number
+// when decoding into a generic tree, to avoid losing precision of integers.
+func unmarshalJSON(data []byte, v interface{}) error {
+	if _, ok := v.(*interface{}); !ok {
+		return json.Unmarshal(data, v)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if dec.More() {
+		return fmt.Errorf("invalid character after top-level value")
+	}
+	return nil
+}
+
+func marshalTOML(v interface{}) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	if err := toml.NewEncoder(buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// RegisterFormat registers the format `name` to the default loader.
+// Caution: global settings are overwritten. can't go back.
+func RegisterFormat(name string, decoder Decoder, encoder Encoder) error {
+	return defaultLoader.RegisterFormat(name, decoder, encoder)
+}
+
+// LoadAs loads files from `configPaths` with Env in the format `name`.
+func LoadAs(name string, conf interface{}, configPaths ...string) error {
+	return defaultLoader.LoadAs(name, conf, configPaths...)
+}
+
+// LoadAsBytes loads bytes with Env in the format `name`.
+func LoadAsBytes(name string, conf interface{}, src []byte) error {
+	return defaultLoader.LoadAsBytes(name, conf, src)
+}
+
+// LoadFiles loads files from `configPaths` in the format detected by their extensions,
+// and assigns decoded values into the `conf` value.
+func LoadFiles(conf interface{}, configPaths ...string) error {
+	return defaultLoader.LoadFiles(conf, configPaths...)
+}
+
+// LoadWithEnvFiles loads files with Env in the format detected by their extensions.
+func LoadWithEnvFiles(conf interface{}, configPaths ...string) error {
+	return defaultLoader.LoadWithEnvFiles(conf, configPaths...)
+}
+
+// RegisterFormat registers the format `name` with its decoder and encoder.
+// The encoder is used to encode values merged from multiple files.
+// Struct tags named `name` are used to map struct fields to keys in error messages.
+// Built-in formats ("yaml", "json" and "toml") can be overridden.
+func (l *Loader) RegisterFormat(name string, decoder Decoder, encoder Encoder) error {
+	if name == "" || decoder == nil || encoder == nil {
+		return fmt.Errorf("format name, decoder and encoder are required")
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	formats := make(map[string]codec, len(l.formats)+1)
+	for n, c := range l.formats {
+		formats[n] = c
+	}
+	formats[name] = codec{name: name, dec: decoder, enc: encoder}
+	l.formats = formats
+	return nil
+}
+
+// RegisterExtension maps the file extension `ext` (e.g. ".yaml.dist") to the format `name`.
+func (l *Loader) RegisterExtension(ext, name string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.lookupCodec(name); !ok {
+		return fmt.Errorf("unknown format %s", name)
+	}
+	extensions := make(map[string]string, len(l.extensions)+1)
+	for e, n := range l.extensions {
+		extensions[e] = n
+	}
+	extensions[strings.ToLower(ext)] = name
+	l.extensions = extensions
+	return nil
+}
+
+// LoadAs loads files from `configPaths` with Env in the format `name`,
+// and assigns decoded values into the `conf` value.
+func (l *Loader) LoadAs(name string, conf interface{}, configPaths ...string) error {
+	return l.loadAs(name, conf, configPaths, l.replacer)
+}
+
+// LoadAsBytes loads bytes with Env in the format `name`.
+func (l *Loader) LoadAsBytes(name string, conf interface{}, src []byte) error {
+	return l.loadAsBytes(name, conf, src, l.replacer)
+}
+
+// LoadFiles loads files from `configPaths` in the format detected by their extensions
+// (.yaml, .yml, .json, .toml and registered ones, optionally followed by .tmpl),
+// and assigns decoded values into the `conf` value.
+// The merged values are decoded in the format of the first path.
+// Glob patterns in `configPaths` are expanded as Glob.
+func (l *Loader) LoadFiles(conf interface{}, configPaths ...string) error {
+	return l.loadGlobWithFunc(conf, l.FS, configPaths, nil)
+}
+
+// LoadWithEnvFiles loads files with Env in the format detected by their extensions.
+// Glob patterns in `configPaths` are expanded as Glob.
+func (l *Loader) LoadWithEnvFiles(conf interface{}, configPaths ...string) error {
+	return l.loadGlobWithFunc(conf, l.FS, configPaths, l.replacer)
+}
+
+// FormatOf returns the format name of `configPath` detected by its extension.
+func (l *Loader) FormatOf(configPath string) (string, error) {
+	name, _, ok := l.detectFormat(configPath)
+	if !ok {
+		return "", fmt.Errorf("%s: unknown config format", configPath)
+	}
+	return name, nil
+}
+
+// detectFormat returns the format name and the matched extension of `configPath`.
+func (l *Loader) detectFormat(configPath string) (name, ext string, ok bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	p := strings.ToLower(filepath.Base(configPath))
+	p = strings.TrimSuffix(p, TemplateExt)
+
+	for _, exts := range []map[string]string{defaultExtensions, l.extensions} {
+		for e, n := range exts {
+			if strings.HasSuffix(p, e) && len(e) >= len(ext) {
+				name, ext = n, e
+			}
+		}
+	}
+	return name, ext, name != ""
+}
+
+func (l *Loader) loadAs(name string, conf interface{}, configPaths []string, custom customFunc) error {
+	c, err := l.codec(name)
+	if err != nil {
+		return err
+	}
+	return l.loadWithFunc(conf, configPaths, custom, c)
+}
+
+func (l *Loader) loadAsBytes(name string, conf interface{}, src []byte, custom customFunc) error {
+	c, err := l.codec(name)
+	if err != nil {
+		return err
+	}
+	return l.loadBytesWithFunc(conf, src, custom, c)
+}
+
+func (l *Loader) codecOf(configPath string) (codec, error) {
+	name, err := l.FormatOf(configPath)
+	if err != nil {
+		return codec{}, err
+	}
+	return l.codec(name)
+}
+
+func (l *Loader) codec(name string) (codec, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	c, ok := l.lookupCodec(name)
+	if !ok {
+		return codec{}, fmt.Errorf("unknown format %s", name)
+	}
+	return c, nil
+}
+
+// lookupCodec must be called with l.mu held.
+func (l *Loader) lookupCodec(name string) (codec, bool) {
+	if c, ok := l.formats[name]; ok {
+		return c, true
+	}
+	c, ok := builtinCodecs[name]
+	return c, ok
+}
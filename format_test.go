@@ -0,0 +1,162 @@
+package config_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/kayac/go-config"
+)
+
+type formatConf struct {
+	Name string            `yaml:"name"`
+	Port int               `yaml:"port"`
+	Opts map[string]string `yaml:"opts"`
+}
+
+func TestLoadWithEnvFiles(t *testing.T) {
+	a, err := genConfigFile("files_a.yml", `
+name: base
+port: 80
+opts: {foo: "1"}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := genConfigFile("files_b.json.tmpl", `{"port": {{ env "FILES_PORT" }}, "opts": {"bar": "2"}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := genConfigFile("files_c.toml", `
+[opts]
+baz = "3"
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("FILES_PORT", "8080")
+
+	var conf formatConf
+	if err := config.LoadWithEnvFiles(&conf, a, b, c); err != nil {
+		t.Fatal(err)
+	}
+	expected := formatConf{
+		Name: "base",
+		Port: 8080,
+		Opts: map[string]string{"foo": "1", "bar": "2", "baz": "3"},
+	}
+	if diff := cmp.Diff(expected, conf); diff != "" {
+		t.Errorf("unexpected config: %s", diff)
+	}
+}
+
+func TestFormatOf(t *testing.T) {
+	loader := config.New()
+	if err := loader.RegisterExtension(".conf", "toml"); err != nil {
+		t.Fatal(err)
+	}
+	if err := loader.RegisterExtension(".ini", "ini"); err == nil {
+		t.Error("unknown format must be an error")
+	}
+	tests := map[string]string{
+		"config.yaml":          "yaml",
+		"config.YML":           "yaml",
+		"/path/to/app.json":    "json",
+		"app.json.tmpl":        "json",
+		"app.toml":             "toml",
+		"app.conf.tmpl":        "toml",
+		"config.yaml.dist":     "",
+		"config.tmpl":          "",
+		"/path/to/config.json": "json",
+	}
+	for path, expected := range tests {
+		name, err := loader.FormatOf(path)
+		if expected == "" {
+			if err == nil {
+				t.Errorf("%s must be unknown format but got %s", path, name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %s", path, err)
+		} else if name != expected {
+			t.Errorf("%s: expected %s got %s", path, expected, name)
+		}
+	}
+}
+
+func TestRegisterFormat(t *testing.T) {
+	// a simple "key=value" format
+	decode := func(data []byte, v interface{}) error {
+		m := make(map[string]interface{})
+		for _, line := range strings.Split(string(data), "\n") {
+			kv := strings.SplitN(strings.TrimSpace(line), "=", 2)
+			if len(kv) == 2 {
+				m[kv[0]] = kv[1]
+			}
+		}
+		switch v := v.(type) {
+		case *interface{}:
+			*v = m
+		case *map[string]interface{}:
+			*v = m
+		default:
+			return fmt.Errorf("unsupported type %T", v)
+		}
+		return nil
+	}
+	encode := func(v interface{}) ([]byte, error) {
+		var b strings.Builder
+		for k, e := range v.(map[string]interface{}) {
+			fmt.Fprintf(&b, "%s=%v\n", k, e)
+		}
+		return []byte(b.String()), nil
+	}
+	loader := config.New()
+	if err := loader.RegisterFormat("kv", config.DecoderFunc(decode), config.EncoderFunc(encode)); err != nil {
+		t.Fatal(err)
+	}
+	if err := loader.RegisterExtension(".kv", "kv"); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("KV_BAR", "baz")
+	a, err := genConfigFile("a.kv", "foo=1\nbar=2\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := genConfigFile("b.kv.tmpl", `bar={{ env "KV_BAR" }}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := make(map[string]interface{})
+	if err := loader.LoadAs("kv", &c, a, b); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{"foo": "1", "bar": "baz"}
+	if diff := cmp.Diff(expected, c); diff != "" {
+		t.Errorf("unexpected config: %s", diff)
+	}
+
+	c2 := make(map[string]interface{})
+	if err := loader.LoadWithEnvFiles(&c2, a, b); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(expected, c2); diff != "" {
+		t.Errorf("unexpected config: %s", diff)
+	}
+
+	c3 := make(map[string]interface{})
+	if err := loader.LoadAsBytes("kv", &c3, []byte(`foo={{ env "KV_BAR" }}`)); err != nil {
+		t.Fatal(err)
+	}
+	if c3["foo"] != "baz" {
+		t.Errorf("unexpected foo: %v", c3["foo"])
+	}
+
+	if err := loader.LoadAs("unknown", &c, a); err == nil {
+		t.Error("unknown format must be an error")
+	}
+}
@@ -0,0 +1,27 @@
+package config
+
+import "io/fs"
+
+// LoadFS loads files from `configPaths` in `fsys` in the format detected by their extensions,
+// and assigns decoded values into the `conf` value.
+func LoadFS(fsys fs.FS, conf interface{}, configPaths ...string) error {
+	return defaultLoader.LoadFS(fsys, conf, configPaths...)
+}
+
+// LoadWithEnvFS loads files in `fsys` with Env in the format detected by their extensions.
+func LoadWithEnvFS(fsys fs.FS, conf interface{}, configPaths ...string) error {
+	return defaultLoader.LoadWithEnvFS(fsys, conf, configPaths...)
+}
+
+// LoadFS loads files from `configPaths` in `fsys` in the format detected by their extensions,
+// and assigns decoded values into the `conf` value.
+// Paths are slash-separated and unrooted as required by fs.FS (e.g. "conf/app.yaml"),
+// and glob patterns are expanded as Loader.Glob.
+func (l *Loader) LoadFS(fsys fs.FS, conf interface{}, configPaths ...string) error {
+	return l.loadGlobWithFunc(conf, fsys, configPaths, nil)
+}
+
+// LoadWithEnvFS loads files in `fsys` with Env in the format detected by their extensions.
+func (l *Loader) LoadWithEnvFS(fsys fs.FS, conf interface{}, configPaths ...string) error {
+	return l.loadGlobWithFunc(conf, fsys, configPaths, l.replacer)
+}
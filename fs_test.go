@@ -0,0 +1,53 @@
+package config_test
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/kayac/go-config"
+)
+
+var testFS = fstest.MapFS{
+	"conf/base.yaml":       {Data: []byte("domain: example.com\nis_dev: false\n")},
+	"conf/local.json.tmpl": {Data: []byte(`{"is_dev": {{ env "FS_IS_DEV" }}}`)},
+	"conf/read.txt":        {Data: []byte(`xxx{{ env "FS_IS_DEV" }}xxx`)},
+}
+
+func TestLoadWithEnvFS(t *testing.T) {
+	t.Setenv("FS_IS_DEV", "true")
+	c := &Conf{}
+	if err := config.LoadWithEnvFS(testFS, c, "conf/base.yaml", "conf/local.json.tmpl"); err != nil {
+		t.Fatal(err)
+	}
+	if c.Domain != "example.com" || !c.IsDev {
+		t.Errorf("unexpected config: %#v", c)
+	}
+
+	if err := config.LoadFS(testFS, c, "conf/nothing.yaml"); err == nil {
+		t.Error("nothing.yaml is not found.")
+	} else {
+		t.Log(err)
+	}
+}
+
+func TestLoaderFS(t *testing.T) {
+	t.Setenv("FS_IS_DEV", "true")
+	loader := config.New()
+	loader.FS = testFS
+
+	c := &Conf{}
+	if err := loader.LoadWithEnv(c, "conf/base.yaml"); err != nil {
+		t.Fatal(err)
+	}
+	if c.Domain != "example.com" {
+		t.Errorf("unexpected config: %#v", c)
+	}
+
+	b, err := loader.ReadWithEnv("conf/read.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "xxxtruexxx" {
+		t.Errorf("unexpected read result: %s", b)
+	}
+}
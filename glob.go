@@ -0,0 +1,235 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// LoadDir loads files in `dir` in the format detected by their extensions,
+// and assigns decoded values into the `conf` value.
+func LoadDir(conf interface{}, dir string) error {
+	return defaultLoader.LoadDir(conf, dir)
+}
+
+// LoadWithEnvDir loads files in `dir` with Env in the format detected by their extensions.
+func LoadWithEnvDir(conf interface{}, dir string) error {
+	return defaultLoader.LoadWithEnvDir(conf, dir)
+}
+
+// LoadDir loads files in `dir` in the format detected by their extensions,
+// and assigns decoded values into the `conf` value.
+// Files are loaded in lexical order. Sub directories, hidden files, backup files
+// and files in unknown formats are skipped.
+func (l *Loader) LoadDir(conf interface{}, dir string) error {
+	paths, err := l.dirFiles(l.FS, dir)
+	if err != nil {
+		return err
+	}
+	return l.loadFilesWithFunc(conf, l.FS, paths, nil, l.codecOf)
+}
+
+// LoadWithEnvDir loads files in `dir` with Env in the format detected by their extensions.
+func (l *Loader) LoadWithEnvDir(conf interface{}, dir string) error {
+	paths, err := l.dirFiles(l.FS, dir)
+	if err != nil {
+		return err
+	}
+	return l.loadFilesWithFunc(conf, l.FS, paths, l.replacer, l.codecOf)
+}
+
+// Glob expands glob patterns in `patterns` into file paths.
+//
+// Patterns are the syntax of path.Match, and "**" matches zero or more directories.
+// Files matched by a pattern are sorted in lexical order, and expanded in the order of `patterns`.
+// Hidden files (".foo") and backup files ("foo~", "foo.bak", "foo.swp", "foo.orig", "#foo#")
+// are skipped. Paths without any glob meta characters are returned as is.
+//
+// If l.GlobMustMatch is true, a pattern which matches no files is an error.
+func (l *Loader) Glob(patterns ...string) ([]string, error) {
+	return l.glob(l.FS, patterns)
+}
+
+// loadGlobWithFunc loads files matched by `patterns` in the format detected by their extensions.
+func (l *Loader) loadGlobWithFunc(conf interface{}, fsys fs.FS, patterns []string, custom customFunc) error {
+	paths, err := l.glob(fsys, patterns)
+	if err != nil {
+		return err
+	}
+	return l.loadFilesWithFunc(conf, fsys, paths, custom, l.codecOf)
+}
+
+func (l *Loader) dirFiles(fsys fs.FS, dir string) ([]string, error) {
+	var entries []fs.DirEntry
+	var err error
+	if fsys == nil {
+		entries, err = os.ReadDir(dir)
+	} else {
+		entries, err = fs.ReadDir(fsys, dir)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s read failed: %w", dir, err)
+	}
+	var paths []string
+	for _, e := range entries {
+		if e.IsDir() || isIgnoredFile(e.Name()) {
+			continue
+		}
+		if _, err := l.FormatOf(e.Name()); err != nil {
+			continue
+		}
+		if fsys == nil {
+			paths = append(paths, filepath.Join(dir, e.Name()))
+		} else {
+			paths = append(paths, path.Join(dir, e.Name()))
+		}
+	}
+	if len(paths) == 0 && l.GlobMustMatch {
+		return nil, fmt.Errorf("%s: no config files found", dir)
+	}
+	return paths, nil
+}
+
+func (l *Loader) glob(fsys fs.FS, patterns []string) ([]string, error) {
+	var paths []string
+	for _, pattern := range patterns {
+		if !hasMeta(pattern) {
+			paths = append(paths, pattern)
+			continue
+		}
+		matches, err := globFiles(fsys, pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%s glob failed: %w", pattern, err)
+		}
+		if len(matches) == 0 && l.GlobMustMatch {
+			return nil, fmt.Errorf("%s: no files matched", pattern)
+		}
+		paths = append(paths, matches...)
+	}
+	return paths, nil
+}
+
+func globFiles(fsys fs.FS, pattern string) ([]string, error) {
+	if fsys == nil {
+		pattern = filepath.ToSlash(pattern)
+	}
+	segs := strings.Split(pattern, "/")
+	i := 0
+	for i < len(segs)-1 && !hasMeta(segs[i]) {
+		i++
+	}
+	root, pat := path.Join(segs[:i]...), segs[i:]
+	if strings.HasPrefix(pattern, "/") {
+		root = "/" + root
+	}
+	if root == "" {
+		root = "."
+	}
+	for _, s := range pat {
+		if _, err := path.Match(s, ""); err != nil {
+			return nil, err
+		}
+	}
+	recursive := false
+	for _, s := range pat {
+		if s == "**" {
+			recursive = true
+		}
+	}
+
+	var matches []string
+	walkFn := func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if p == root && errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if fsys == nil {
+			p = filepath.ToSlash(p)
+		}
+		if p == root {
+			return nil
+		}
+		rel := strings.TrimPrefix(p, root+"/")
+		if root == "." {
+			rel = p
+		}
+		name := strings.Split(rel, "/")
+		if d.IsDir() {
+			if isIgnoredFile(d.Name()) || (!recursive && len(name) >= len(pat)) {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !isIgnoredFile(d.Name()) && matchSegments(pat, name) {
+			matches = append(matches, p)
+		}
+		return nil
+	}
+	var err error
+	if fsys == nil {
+		err = filepath.WalkDir(filepath.FromSlash(root), walkFn)
+	} else {
+		err = fs.WalkDir(fsys, root, walkFn)
+	}
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(matches)
+	if fsys == nil {
+		for i, m := range matches {
+			matches[i] = filepath.FromSlash(m)
+		}
+	}
+	return matches, nil
+}
+
+// matchSegments reports whether name matches pattern, both split by "/".
+// "**" in pattern matches zero or more segments.
+func matchSegments(pattern, name []string) bool {
+	if len(pattern) == 0 {
+		return len(name) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(name); i++ {
+			if matchSegments(pattern[1:], name[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(name) == 0 {
+		return false
+	}
+	if ok, _ := path.Match(pattern[0], name[0]); !ok {
+		return false
+	}
+	return matchSegments(pattern[1:], name[1:])
+}
+
+func hasMeta(p string) bool {
+	return strings.ContainsAny(p, `*?[`)
+}
+
+// isIgnoredFile reports whether name is a hidden file or a backup file.
+func isIgnoredFile(name string) bool {
+	switch {
+	case strings.HasPrefix(name, "."):
+		return true
+	case strings.HasPrefix(name, "#") && strings.HasSuffix(name, "#"):
+		return true
+	case strings.HasSuffix(name, "~"):
+		return true
+	}
+	switch path.Ext(name) {
+	case ".bak", ".swp", ".orig":
+		return true
+	}
+	return false
+}
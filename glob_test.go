@@ -0,0 +1,102 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/kayac/go-config"
+)
+
+var globFS = fstest.MapFS{
+	"conf.d/10-base.yaml":       {Data: []byte("name: base\nport: 80\n")},
+	"conf.d/20-port.json":       {Data: []byte(`{"port": 8080}`)},
+	"conf.d/30-name.yaml":       {Data: []byte("name: overlay\n")},
+	"conf.d/30-name.yaml~":      {Data: []byte("name: backup\n")},
+	"conf.d/.hidden.yaml":       {Data: []byte("name: hidden\n")},
+	"conf.d/README.md":          {Data: []byte("# README\n")},
+	"conf.d/sub/40-opts.yaml":   {Data: []byte("opts: {foo: bar}\n")},
+	"conf.d/.git/50-opts.yaml":  {Data: []byte("opts: {foo: git}\n")},
+	"conf.d/sub/deep/60.yaml":   {Data: []byte("opts: {bar: baz}\n")},
+	"conf.d/sub/deep/70.yaml.b": {Data: []byte("opts: {bar: bak}\n")},
+}
+
+func TestGlob(t *testing.T) {
+	loader := config.New()
+	loader.FS = globFS
+	tests := map[string][]string{
+		"conf.d/*.yaml": {"conf.d/10-base.yaml", "conf.d/30-name.yaml"},
+		"conf.d/**/*.yaml": {
+			"conf.d/10-base.yaml",
+			"conf.d/30-name.yaml",
+			"conf.d/sub/40-opts.yaml",
+			"conf.d/sub/deep/60.yaml",
+		},
+		"conf.d/*/*.yaml":     {"conf.d/sub/40-opts.yaml"},
+		"conf.d/nothing/*":    nil,
+		"conf.d/10-base.yaml": {"conf.d/10-base.yaml"},
+	}
+	for pattern, expected := range tests {
+		paths, err := loader.Glob(pattern)
+		if err != nil {
+			t.Errorf("%s: %s", pattern, err)
+			continue
+		}
+		if diff := cmp.Diff(expected, paths); diff != "" {
+			t.Errorf("%s: unexpected paths: %s", pattern, diff)
+		}
+	}
+
+	loader.GlobMustMatch = true
+	if _, err := loader.Glob("conf.d/*.toml"); err == nil {
+		t.Error("no match must be an error")
+	}
+}
+
+func TestLoadDir(t *testing.T) {
+	loader := config.New()
+	loader.FS = globFS
+	var c formatConf
+	if err := loader.LoadDir(&c, "conf.d"); err != nil {
+		t.Fatal(err)
+	}
+	expected := formatConf{Name: "overlay", Port: 8080}
+	if diff := cmp.Diff(expected, c); diff != "" {
+		t.Errorf("unexpected config: %s", diff)
+	}
+
+	var c2 formatConf
+	if err := loader.LoadFiles(&c2, "conf.d/*.yaml", "conf.d/**/6*.yaml"); err != nil {
+		t.Fatal(err)
+	}
+	expected = formatConf{Name: "overlay", Port: 80, Opts: map[string]string{"bar": "baz"}}
+	if diff := cmp.Diff(expected, c2); diff != "" {
+		t.Errorf("unexpected config: %s", diff)
+	}
+}
+
+func TestGlobOS(t *testing.T) {
+	d := filepath.Join(dir, "glob")
+	if err := os.MkdirAll(filepath.Join(d, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"b.yaml", "a.yaml", "sub/c.yaml", ".d.yaml"} {
+		if err := os.WriteFile(filepath.Join(d, name), []byte("foo: "+name+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	paths, err := config.New().Glob(filepath.Join(d, "**", "*.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		filepath.Join(d, "a.yaml"),
+		filepath.Join(d, "b.yaml"),
+		filepath.Join(d, "sub", "c.yaml"),
+	}
+	if diff := cmp.Diff(expected, paths); diff != "" {
+		t.Errorf("unexpected paths: %s", diff)
+	}
+}
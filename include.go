@@ -0,0 +1,53 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// includeFunc returns the template function "include" for the document `src`.
+//
+// {{ include "db.yaml" }} renders db.yaml by the same Loader and inserts it.
+// A relative path is resolved against the directory of the including file.
+// To include a YAML document as a nested value, indent it by {{ include "db.yaml" | nindent 2 }}.
+func (l *Loader) includeFunc(src source) func(string) (string, error) {
+	return func(name string) (string, error) {
+		p := resolvePath(src, name)
+		stack := append(src.includedBy[:len(src.includedBy):len(src.includedBy)], src.name)
+		for _, s := range stack {
+			if s == p {
+				return "", fmt.Errorf("include cycle detected: %s -> %s", strings.Join(stack, " -> "), p)
+			}
+		}
+		data, err := readFile(src.fsys, p)
+		if err != nil {
+			return "", fmt.Errorf("%s read failed: %w", p, err)
+		}
+		b, err := l.replacer(data, source{fsys: src.fsys, name: p, includedBy: stack, render: src.render})
+		if err != nil {
+			return "", fmt.Errorf("%s include failed: %w", p, err)
+		}
+		return string(b), nil
+	}
+}
+
+// resolvePath resolves `name` against the directory of src.
+func resolvePath(src source, name string) string {
+	if src.fsys != nil {
+		if src.name == "" || path.IsAbs(name) {
+			return path.Clean(name)
+		}
+		return path.Join(path.Dir(src.name), name)
+	}
+	if src.name == "" || filepath.IsAbs(name) {
+		return filepath.Clean(name)
+	}
+	return filepath.Join(filepath.Dir(src.name), name)
+}
+
+func indent(spaces int, s string) string {
+	pad := strings.Repeat(" ", spaces)
+	return pad + strings.Replace(s, "\n", "\n"+pad, -1)
+}
@@ -0,0 +1,77 @@
+package config_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/kayac/go-config"
+)
+
+var includeFS = fstest.MapFS{
+	"conf/app.yaml": {Data: []byte(`domain: {{ .domain }}
+db:{{ include "parts/db.yaml" | nindent 2 }}
+`)},
+	"conf/parts/db.yaml": {Data: []byte(`master: {{ env "INCLUDE_DB_USER" "rw" }}@/example
+{{ include "slave.yaml" }}`)},
+	"conf/parts/slave.yaml":   {Data: []byte(`slave: ro@/example`)},
+	"conf/cycle_a.yaml":       {Data: []byte(`{{ include "cycle_b.yaml" }}`)},
+	"conf/cycle_b.yaml":       {Data: []byte(`{{ include "./cycle_a.yaml" }}`)},
+	"conf/missing.yaml":       {Data: []byte("domain: example.com\n{{ include \"parts/missing.yaml\" }}\n")},
+	"conf/parts/missing.yaml": {Data: []byte("db:\n  master: {{ must_env \"INCLUDE_MISSING\" }}\n")},
+}
+
+func TestInclude(t *testing.T) {
+	t.Setenv("INCLUDE_DB_USER", "admin")
+	loader := config.New()
+	loader.FS = includeFS
+	loader.Data = map[string]string{"domain": "example.com"}
+
+	c := &Conf{}
+	if err := loader.LoadWithEnv(c, "conf/app.yaml"); err != nil {
+		t.Fatal(err)
+	}
+	expected := &Conf{
+		Domain: "example.com",
+		DB: DBConfig{
+			Master: "admin@/example",
+			Slave:  "ro@/example",
+		},
+	}
+	if diff := cmp.Diff(expected, c); diff != "" {
+		t.Errorf("unexpected config: %s", diff)
+	}
+}
+
+func TestIncludeCycle(t *testing.T) {
+	loader := config.New()
+	loader.FS = includeFS
+
+	c := &Conf{}
+	err := loader.LoadWithEnv(c, "conf/cycle_a.yaml")
+	if err == nil {
+		t.Fatal("include cycle must be an error")
+	}
+	if !strings.Contains(err.Error(), "include cycle detected") {
+		t.Errorf("unexpected error: %s", err)
+	}
+	t.Log(err)
+}
+
+func TestIncludeMissingEnv(t *testing.T) {
+	loader := config.New()
+	loader.FS = includeFS
+
+	c := &Conf{}
+	err := loader.LoadWithEnv(c, "conf/missing.yaml")
+	var me *config.MissingEnvError
+	if !errors.As(err, &me) {
+		t.Fatalf("expected MissingEnvError, got %v", err)
+	}
+	expected := config.MissingEnvError{Name: "INCLUDE_MISSING", Source: "conf/parts/missing.yaml", Line: 2}
+	if *me != expected {
+		t.Errorf("unexpected error: %#v", me)
+	}
+}
@@ -0,0 +1,74 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+)
+
+// fieldKey returns the config key of the struct field `f` by the struct tag `tag`.
+// inline reports the fields of `f` are decoded at the same level.
+func fieldKey(f reflect.StructField, tag string) (key string, inline, ok bool) {
+	name, opts := f.Tag.Get(tag), ""
+	if i := strings.Index(name, ","); i >= 0 {
+		name, opts = name[:i], name[i+1:]
+	}
+	if name == "-" {
+		return "", false, false
+	}
+	for _, o := range strings.Split(opts, ",") {
+		if o == "inline" {
+			return "", true, true
+		}
+	}
+	if name != "" {
+		return name, false, true
+	}
+	if f.Anonymous && indirectType(f.Type).Kind() == reflect.Struct && tag != "yaml" {
+		return "", true, true
+	}
+	if tag == "yaml" {
+		return strings.ToLower(f.Name), false, true
+	}
+	return f.Name, false, true
+}
+
+// lookupKey returns the value of `key` in the tree `v`.
+// Keys are matched case-insensitively if no exact match is found.
+func lookupKey(v interface{}, key string) (interface{}, bool) {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	if e, ok := m[key]; ok {
+		return e, true
+	}
+	for k, e := range m {
+		if strings.EqualFold(k, key) {
+			return e, true
+		}
+	}
+	return nil, false
+}
+
+// sourceOf returns the name of the last document which has the value at `keys`.
+// Keys under a slice are attributed to the document which has the slice.
+func sourceOf(docs []document, keys []string) string {
+	if len(keys) == 0 {
+		return ""
+	}
+	for i := len(docs) - 1; i >= 0; i-- {
+		v, found := docs[i].tree, true
+		for _, k := range keys {
+			if _, isSlice := v.([]interface{}); isSlice {
+				break
+			}
+			if v, found = lookupKey(v, k); !found {
+				break
+			}
+		}
+		if found {
+			return docs[i].name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,237 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// MergeMode represents how a value in a later config file is merged into
+// the value decoded from earlier files.
+type MergeMode int
+
+const (
+	// MergeDefault merges maps recursively and replaces any other values.
+	MergeDefault MergeMode = iota
+	// MergeReplace replaces the value entirely, even if both values are maps.
+	MergeReplace
+	// MergeAppend appends the elements of a later slice to an earlier one.
+	MergeAppend
+	// MergeByKey merges slices of maps. Elements which have the same value
+	// for MergeStrategy.Key are merged recursively, others are appended.
+	MergeByKey
+)
+
+// MergeStrategy defines how values at a path are merged.
+type MergeStrategy struct {
+	Mode MergeMode
+	Key  string // used by MergeByKey
+}
+
+// SetMergeStrategy sets the merge strategy for the value at `path`.
+// path is a dot separated list of keys (e.g. "db.replicas"),
+// and "*" matches any single key. Slice elements don't add a path element.
+func (l *Loader) SetMergeStrategy(path string, s MergeStrategy) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	strategies := make(map[string]MergeStrategy, len(l.strategies)+1)
+	for p, v := range l.strategies {
+		strategies[p] = v
+	}
+	if _, ok := strategies[path]; !ok {
+		l.strategyPaths = append(l.strategyPaths[:len(l.strategyPaths):len(l.strategyPaths)], path)
+	}
+	strategies[path] = s
+	l.strategies = strategies
+}
+
+func (l *Loader) newMerger() *merger {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	m := &merger{strategies: make(map[string]MergeStrategy, len(l.strategies))}
+	for _, path := range l.strategyPaths {
+		m.strategies[path] = l.strategies[path]
+		if strings.Contains(path, "*") {
+			m.patterns = append(m.patterns, path)
+		}
+	}
+	// the most specific pattern wins, and then the earliest registered one
+	sort.SliceStable(m.patterns, func(i, j int) bool {
+		return strings.Count(m.patterns[i], "*") < strings.Count(m.patterns[j], "*")
+	})
+	return m
+}
+
+type merger struct {
+	strategies map[string]MergeStrategy
+	patterns   []string // paths which have "*", in the order of precedence
+}
+
+func (m *merger) lookup(path string) MergeStrategy {
+	if s, ok := m.strategies[path]; ok {
+		return s
+	}
+	if path == "" { // the root is never matched by patterns
+		return MergeStrategy{}
+	}
+	for _, p := range m.patterns {
+		if matchPath(p, path) {
+			return m.strategies[p]
+		}
+	}
+	return MergeStrategy{}
+}
+
+func matchPath(pattern, path string) bool {
+	ps := strings.Split(pattern, ".")
+	ks := strings.Split(path, ".")
+	if len(ps) != len(ks) {
+		return false
+	}
+	for i := range ps {
+		if ps[i] != "*" && ps[i] != ks[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// merge merges src into dst and returns the result. dst may be modified.
+func (m *merger) merge(path string, dst, src interface{}) interface{} {
+	s := m.lookup(path)
+	switch s.Mode {
+	case MergeReplace:
+		return src
+	case MergeAppend:
+		d, dok := dst.([]interface{})
+		v, sok := src.([]interface{})
+		if dok && sok {
+			return append(append(make([]interface{}, 0, len(d)+len(v)), d...), v...)
+		}
+	case MergeByKey:
+		d, dok := dst.([]interface{})
+		v, sok := src.([]interface{})
+		if dok && sok {
+			return m.mergeByKey(path, d, v, s.Key)
+		}
+	}
+
+	dm, dok := dst.(map[string]interface{})
+	sm, sok := src.(map[string]interface{})
+	if !dok || !sok {
+		return src
+	}
+	for k, v := range sm {
+		if dv, ok := dm[k]; ok {
+			dm[k] = m.merge(joinPath(path, k), dv, v)
+		} else {
+			dm[k] = v
+		}
+	}
+	return dm
+}
+
+func (m *merger) mergeByKey(path string, dst, src []interface{}, key string) []interface{} {
+	res := append(make([]interface{}, 0, len(dst)+len(src)), dst...)
+	index := make(map[string]int, len(dst))
+	for i, e := range res {
+		if k, ok := elementKey(e, key); ok {
+			index[k] = i
+		}
+	}
+	for _, e := range src {
+		k, ok := elementKey(e, key)
+		if !ok {
+			res = append(res, e)
+			continue
+		}
+		if i, found := index[k]; found {
+			res[i] = m.merge(path, res[i], e)
+		} else {
+			index[k] = len(res)
+			res = append(res, e)
+		}
+	}
+	return res
+}
+
+func elementKey(e interface{}, key string) (string, bool) {
+	em, ok := e.(map[string]interface{})
+	if !ok {
+		return "", false
+	}
+	v, ok := em[key]
+	if !ok {
+		return "", false
+	}
+	return fmt.Sprint(v), true
+}
+
+// normalize converts map[interface{}]interface{} decoded by YAML into
+// map[string]interface{}, and []map[string]interface{} decoded by TOML into
+// []interface{} recursively.
+func normalize(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[fmt.Sprint(k)] = normalize(e)
+		}
+		return m
+	case map[string]interface{}:
+		for k, e := range v {
+			v[k] = normalize(e)
+		}
+		return v
+	case []interface{}:
+		for i, e := range v {
+			v[i] = normalize(e)
+		}
+		return v
+	case []map[string]interface{}:
+		s := make([]interface{}, len(v))
+		for i, e := range v {
+			s[i] = normalize(e)
+		}
+		return s
+	}
+	return v
+}
+
+// tree decodes `data` into a tree.
+func (c codec) tree(data []byte) (interface{}, error) {
+	if c.decodeTree != nil {
+		return c.decodeTree(data)
+	}
+	var v interface{}
+	if err := c.dec.Decode(data, &v); err != nil {
+		return nil, err
+	}
+	return normalize(v), nil
+}
+
+// copyTree returns a deep copy of the tree `v`.
+func copyTree(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[k] = copyTree(e)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(v))
+		for i, e := range v {
+			s[i] = copyTree(e)
+		}
+		return s
+	}
+	return v
+}
@@ -0,0 +1,287 @@
+package config_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/kayac/go-config"
+	"gopkg.in/yaml.v2"
+)
+
+type mergeConf struct {
+	Name  string            `yaml:"name" json:"name" toml:"name"`
+	Tags  []string          `yaml:"tags" json:"tags" toml:"tags"`
+	Hosts []string          `yaml:"hosts" json:"hosts" toml:"hosts"`
+	Opts  map[string]string `yaml:"opts" json:"opts" toml:"opts"`
+	DBs   []mergeDB         `yaml:"dbs" json:"dbs" toml:"dbs"`
+}
+
+type mergeDB struct {
+	Name string `yaml:"name" json:"name" toml:"name"`
+	Host string `yaml:"host" json:"host" toml:"host"`
+	Port int    `yaml:"port" json:"port" toml:"port"`
+}
+
+var mergeTests = []struct {
+	name  string
+	load  func(*config.Loader, interface{}, ...string) error
+	files [2]string
+}{
+	{
+		name: "yaml",
+		load: (*config.Loader).Load,
+		files: [2]string{`
+name: base
+tags: [a, b]
+hosts: [h1]
+opts: {foo: "1", bar: "2"}
+dbs:
+  - {name: master, host: m1, port: 3306}
+  - {name: slave, host: s1, port: 3306}
+`, `
+tags: [c]
+hosts: [h2]
+opts: {bar: "3"}
+dbs:
+  - {name: slave, host: s2}
+  - {name: extra, host: e1, port: 3307}
+`},
+	},
+	{
+		name: "json",
+		load: (*config.Loader).LoadJSON,
+		files: [2]string{`{
+  "name": "base",
+  "tags": ["a", "b"],
+  "hosts": ["h1"],
+  "opts": {"foo": "1", "bar": "2"},
+  "dbs": [
+    {"name": "master", "host": "m1", "port": 3306},
+    {"name": "slave", "host": "s1", "port": 3306}
+  ]
+}`, `{
+  "tags": ["c"],
+  "hosts": ["h2"],
+  "opts": {"bar": "3"},
+  "dbs": [
+    {"name": "slave", "host": "s2"},
+    {"name": "extra", "host": "e1", "port": 3307}
+  ]
+}`},
+	},
+	{
+		name: "toml",
+		load: (*config.Loader).LoadTOML,
+		files: [2]string{`
+name = "base"
+tags = ["a", "b"]
+hosts = ["h1"]
+[opts]
+foo = "1"
+bar = "2"
+[[dbs]]
+name = "master"
+host = "m1"
+port = 3306
+[[dbs]]
+name = "slave"
+host = "s1"
+port = 3306
+`, `
+tags = ["c"]
+hosts = ["h2"]
+[opts]
+bar = "3"
+[[dbs]]
+name = "slave"
+host = "s2"
+[[dbs]]
+name = "extra"
+host = "e1"
+port = 3307
+`},
+	},
+}
+
+func TestMergeStrategy(t *testing.T) {
+	expected := mergeConf{
+		Name:  "base",
+		Tags:  []string{"a", "b", "c"},
+		Hosts: []string{"h2"},
+		Opts:  map[string]string{"foo": "1", "bar": "3"},
+		DBs: []mergeDB{
+			{Name: "master", Host: "m1", Port: 3306},
+			{Name: "slave", Host: "s2", Port: 3306},
+			{Name: "extra", Host: "e1", Port: 3307},
+		},
+	}
+	for _, ts := range mergeTests {
+		t.Run(ts.name, func(t *testing.T) {
+			base, err := genConfigFile("merge_base."+ts.name, ts.files[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+			local, err := genConfigFile("merge_local."+ts.name, ts.files[1])
+			if err != nil {
+				t.Fatal(err)
+			}
+			loader := config.New()
+			loader.SetMergeStrategy("tags", config.MergeStrategy{Mode: config.MergeAppend})
+			loader.SetMergeStrategy("dbs", config.MergeStrategy{Mode: config.MergeByKey, Key: "name"})
+
+			var c mergeConf
+			if err := ts.load(loader, &c, base, local); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(expected, c); diff != "" {
+				t.Errorf("unexpected merged config: %s", diff)
+			}
+		})
+	}
+}
+
+func TestMergeReplace(t *testing.T) {
+	base, err := genConfigFile("replace_base.yml", `
+name: base
+opts: {foo: "1", bar: "2"}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	local, err := genConfigFile("replace_local.yml", `
+opts: {bar: "3"}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loader := config.New()
+	loader.SetMergeStrategy("*", config.MergeStrategy{Mode: config.MergeReplace})
+
+	var c mergeConf
+	if err := loader.Load(&c, base, local); err != nil {
+		t.Fatal(err)
+	}
+	expected := mergeConf{Name: "base", Opts: map[string]string{"bar": "3"}}
+	if diff := cmp.Diff(expected, c); diff != "" {
+		t.Errorf("unexpected merged config: %s", diff)
+	}
+}
+
+func TestMergeStrategyPrecedence(t *testing.T) {
+	base, err := genConfigFile("precedence_base.yml", `
+a: {x: {k1: 1}, z: {k1: 1}}
+b: {x: {k1: 1}}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	local, err := genConfigFile("precedence_local.yml", `
+a: {x: {k2: 2}, z: {k2: 2}}
+b: {x: {k2: 2}}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]map[string]map[string]int{
+		"a": {"x": {"k2": 2}, "z": {"k1": 1, "k2": 2}},
+		"b": {"x": {"k2": 2}},
+	}
+	for i := 0; i < 20; i++ {
+		loader := config.New()
+		loader.SetMergeStrategy("*.*", config.MergeStrategy{Mode: config.MergeReplace})
+		loader.SetMergeStrategy("*.x", config.MergeStrategy{Mode: config.MergeReplace})
+		loader.SetMergeStrategy("a.*", config.MergeStrategy{Mode: config.MergeDefault})
+
+		var c map[string]map[string]map[string]int
+		if err := loader.Load(&c, base, local); err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(expected, c); diff != "" {
+			t.Fatalf("unexpected merged config: %s", diff)
+		}
+	}
+}
+
+type scalarConf struct {
+	Version string          `yaml:"version"`
+	Zip     string          `yaml:"zip"`
+	Flag    string          `yaml:"flag"`
+	Ratio   float64         `yaml:"ratio"`
+	At      time.Time       `yaml:"at"`
+	Ints    map[int]string  `yaml:"ints"`
+	Bools   map[bool]string `yaml:"bools"`
+	Port    int             `yaml:"port"`
+}
+
+var scalarFS = fstest.MapFS{
+	"base.yaml": {Data: []byte(`version: 1.10
+zip: 0x1F
+flag: yes
+ratio: 0.5
+at: 2023-01-02T03:04:05Z
+ints: {1: one}
+bools: {true: "on"}
+`)},
+	"local.yaml":   {Data: []byte("ints: {2: two}\nbools: {false: \"off\"}\n")},
+	"invalid.yaml": {Data: []byte("# invalid port\nversion: 1.10\n\nport: abc\n")},
+}
+
+func TestLoadKeepsScalars(t *testing.T) {
+	at := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		paths    []string
+		expected scalarConf
+	}{
+		{
+			[]string{"base.yaml"},
+			scalarConf{
+				Version: "1.10", Zip: "0x1F", Flag: "yes", Ratio: 0.5, At: at,
+				Ints:  map[int]string{1: "one"},
+				Bools: map[bool]string{true: "on"},
+			},
+		},
+		{
+			[]string{"base.yaml", "local.yaml"},
+			scalarConf{
+				Version: "1.10", Zip: "0x1F", Flag: "yes", Ratio: 0.5, At: at,
+				Ints:  map[int]string{1: "one", 2: "two"},
+				Bools: map[bool]string{true: "on", false: "off"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		loader := config.New()
+		loader.FS = scalarFS
+		var c scalarConf
+		if err := loader.LoadFiles(&c, tt.paths...); err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(tt.expected, c); diff != "" {
+			t.Errorf("%v: unexpected config (-want +got):\n%s", tt.paths, diff)
+		}
+	}
+}
+
+func TestLoadTypeErrorSource(t *testing.T) {
+	loader := config.New()
+	loader.FS = scalarFS
+
+	var c scalarConf
+	err := loader.LoadFiles(&c, "invalid.yaml")
+	if err == nil || !strings.Contains(err.Error(), "invalid.yaml load failed: parse failed: yaml: unmarshal errors:\n  line 4: cannot unmarshal") {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	err = loader.LoadFiles(&c, "base.yaml", "invalid.yaml", "local.yaml")
+	var te *yaml.TypeError
+	if !errors.As(err, &te) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(te.Errors) != 1 || te.Errors[0] != "invalid.yaml:4: port: cannot unmarshal !!str `abc` into int" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
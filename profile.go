@@ -0,0 +1,116 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// DefaultLocalProfile is the profile applied after all active profiles, if its overlay exists.
+// Loader.LocalProfile overrides it.
+const DefaultLocalProfile = "local"
+
+// NoLocalProfile disables the local profile when set to Loader.LocalProfile.
+const NoLocalProfile = "-"
+
+// LoadWithEnvProfiles loads `basePath` and its overlays for `profiles` with Env.
+func LoadWithEnvProfiles(conf interface{}, basePath string, profiles ...string) ([]string, error) {
+	return defaultLoader.LoadWithEnvProfiles(conf, basePath, profiles...)
+}
+
+// ProfilesFromEnv returns comma separated profiles in the environment variable `key`.
+func ProfilesFromEnv(key string) []string {
+	return defaultLoader.ProfilesFromEnv(key)
+}
+
+// LoadWithEnvProfiles loads `basePath` and its overlays for `profiles` with Env,
+// in the format detected by their extensions, and returns the paths of applied overlays.
+//
+// An overlay path is `basePath` with the profile name inserted before its extension.
+// e.g. for "config.yaml" with profiles "production" and the local profile,
+// "config.yaml", "config.production.yaml" and "config.local.yaml" are loaded in this order.
+// Overlays which don't exist are skipped.
+func (l *Loader) LoadWithEnvProfiles(conf interface{}, basePath string, profiles ...string) ([]string, error) {
+	overlays, err := l.ProfilePaths(basePath, profiles...)
+	if err != nil {
+		return nil, err
+	}
+	paths := append([]string{basePath}, overlays...)
+	if err := l.loadFilesWithFunc(conf, l.FS, paths, l.replacer, l.codecOf); err != nil {
+		return nil, err
+	}
+	return overlays, nil
+}
+
+// ProfilePaths returns the paths of existing overlays of `basePath` for `profiles`
+// and the local profile, in the order to be applied.
+func (l *Loader) ProfilePaths(basePath string, profiles ...string) ([]string, error) {
+	if local := l.localProfile(); local != "" {
+		profiles = append(profiles[:len(profiles):len(profiles)], local)
+	}
+	var paths []string
+	for _, profile := range profiles {
+		p := l.profilePath(basePath, profile)
+		exists, err := fileExists(l.FS, p)
+		if err != nil {
+			return nil, fmt.Errorf("%s stat failed: %w", p, err)
+		}
+		if exists {
+			paths = append(paths, p)
+		}
+	}
+	return paths, nil
+}
+
+// ProfilesFromEnv returns comma separated profiles in the environment variable `key`,
+// looked up in l.Env and l.Dotenv. e.g. APP_ENV=production,canary returns ["production", "canary"].
+func (l *Loader) ProfilesFromEnv(key string) []string {
+	var profiles []string
+	v, _ := l.lookupEnv(key)
+	for _, p := range strings.Split(v, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			profiles = append(profiles, p)
+		}
+	}
+	return profiles
+}
+
+func (l *Loader) localProfile() string {
+	switch l.LocalProfile {
+	case "":
+		return DefaultLocalProfile
+	case NoLocalProfile:
+		return ""
+	}
+	return l.LocalProfile
+}
+
+func (l *Loader) profilePath(basePath, profile string) string {
+	tmpl := ""
+	if strings.HasSuffix(strings.ToLower(basePath), TemplateExt) {
+		tmpl = basePath[len(basePath)-len(TemplateExt):]
+		basePath = basePath[:len(basePath)-len(TemplateExt)]
+	}
+	ext := ""
+	if _, e, ok := l.detectFormat(basePath); ok {
+		ext = basePath[len(basePath)-len(e):]
+	} else if i := strings.LastIndex(basePath, "."); i > strings.LastIndexAny(basePath, `/\`) {
+		ext = basePath[i:]
+	}
+	return basePath[:len(basePath)-len(ext)] + "." + profile + ext + tmpl
+}
+
+func fileExists(fsys fs.FS, name string) (bool, error) {
+	var err error
+	if fsys == nil {
+		_, err = os.Stat(name)
+	} else {
+		_, err = fs.Stat(fsys, name)
+	}
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
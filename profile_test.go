@@ -0,0 +1,80 @@
+package config_test
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/kayac/go-config"
+)
+
+var profileFS = fstest.MapFS{
+	"config.yaml":            {Data: []byte("name: base\nport: 80\n")},
+	"config.production.yaml": {Data: []byte("port: {{ env \"PROFILE_PORT\" \"443\" }}\n")},
+	"config.local.yaml":      {Data: []byte("name: local\n")},
+	"app.json.tmpl":          {Data: []byte(`{"name": "app"}`)},
+	"app.staging.json.tmpl":  {Data: []byte(`{"port": 8080}`)},
+}
+
+func TestLoadWithEnvProfiles(t *testing.T) {
+	t.Setenv("APP_ENV", "production, canary")
+	loader := config.New()
+	loader.FS = profileFS
+
+	profiles := loader.ProfilesFromEnv("APP_ENV")
+	if diff := cmp.Diff([]string{"production", "canary"}, profiles); diff != "" {
+		t.Errorf("unexpected profiles: %s", diff)
+	}
+
+	var c formatConf
+	applied, err := loader.LoadWithEnvProfiles(&c, "config.yaml", profiles...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"config.production.yaml", "config.local.yaml"}, applied); diff != "" {
+		t.Errorf("unexpected applied overlays: %s", diff)
+	}
+	if diff := cmp.Diff(formatConf{Name: "local", Port: 443}, c); diff != "" {
+		t.Errorf("unexpected config: %s", diff)
+	}
+
+	var c2 formatConf
+	applied, err = loader.LoadWithEnvProfiles(&c2, "app.json.tmpl", "staging")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"app.staging.json.tmpl"}, applied); diff != "" {
+		t.Errorf("unexpected applied overlays: %s", diff)
+	}
+	if diff := cmp.Diff(formatConf{Name: "app", Port: 8080}, c2); diff != "" {
+		t.Errorf("unexpected config: %s", diff)
+	}
+}
+
+func TestLocalProfile(t *testing.T) {
+	fsys := fstest.MapFS{
+		"config.yaml":       {Data: []byte("name: base\n")},
+		"config.local.yaml": {Data: []byte("name: local\n")},
+		"config.dev.yaml":   {Data: []byte("name: dev\n")},
+	}
+	tests := []struct {
+		local    string
+		expected []string
+	}{
+		{"", []string{"config.local.yaml"}},
+		{"dev", []string{"config.dev.yaml"}},
+		{config.NoLocalProfile, nil},
+	}
+	for _, tt := range tests {
+		loader := config.New()
+		loader.FS = fsys
+		loader.LocalProfile = tt.local
+		paths, err := loader.WithData(nil).ProfilePaths("config.yaml")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(tt.expected, paths); diff != "" {
+			t.Errorf("LocalProfile %q: unexpected paths: %s", tt.local, diff)
+		}
+	}
+}
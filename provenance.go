@@ -0,0 +1,246 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+// Origin represents where a config value came from.
+type Origin struct {
+	Source   string // the file which set the value, empty for bytes and readers
+	Line     int    // line number of the key in the source before rendering, 0 if unknown
+	Template bool   // whether the value is rendered by a template action
+}
+
+// Provenance maps paths of config keys (e.g. "db.master") to the origins of their values.
+// Arrays are tracked as a whole, by the last file which set them.
+type Provenance map[string]Origin
+
+// LoadWithProvenance loads files with Env as LoadWithEnvFiles,
+// and returns the origins of loaded values.
+func (l *Loader) LoadWithProvenance(conf interface{}, configPaths ...string) (Provenance, error) {
+	paths, err := l.glob(l.FS, configPaths)
+	if err != nil {
+		return nil, err
+	}
+	return l.loadProvenance(conf, paths, l.codecOf)
+}
+
+// LoadAsWithProvenance loads files with Env in the format `name` as LoadAs,
+// and returns the origins of loaded values.
+func (l *Loader) LoadAsWithProvenance(name string, conf interface{}, configPaths ...string) (Provenance, error) {
+	c, err := l.codec(name)
+	if err != nil {
+		return nil, err
+	}
+	return l.loadProvenance(conf, configPaths, func(string) (codec, error) {
+		return c, nil
+	})
+}
+
+func (l *Loader) loadProvenance(conf interface{}, configPaths []string, codecOf func(string) (codec, error)) (Provenance, error) {
+	docs, tree, c, err := l.mergeFiles(l.FS, configPaths, l.replacer, codecOf)
+	if err != nil {
+		return nil, err
+	}
+	p := l.provenance(docs, tree)
+	if err := l.decode(conf, docs, tree, c); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// provenance returns the origins of values in the merged `tree` of `docs`.
+// Line numbers are found in YAML, JSON and TOML documents only.
+func (l *Loader) provenance(docs []document, tree interface{}) Provenance {
+	l.mu.Lock()
+	left, right := l.leftDelim, l.rightDelim
+	l.mu.Unlock()
+	if left == "" || right == "" {
+		left, right = "{{", "}}"
+	}
+
+	p := make(Provenance)
+	for _, doc := range docs {
+		rendered := !bytes.Equal(doc.raw, doc.data)
+		var keyLines map[string]int
+		switch doc.codec.name {
+		case "yaml":
+			keyLines = yamlKeyLines(doc.raw)
+		case "json":
+			keyLines = jsonKeyLines(doc.raw, left, right)
+		case "toml":
+			keyLines = tomlKeyLines(doc.raw)
+		}
+		lines := strings.Split(string(doc.raw), "\n")
+		for _, path := range leafPaths(doc.tree, "") {
+			o := Origin{Source: doc.name}
+			if n, ok := keyLines[path]; ok {
+				o.Line = n
+				o.Template = rendered && strings.Contains(lines[n-1], left)
+			} else {
+				o.Template = rendered && keyLines != nil // generated by the template
+			}
+			p[path] = o
+		}
+	}
+
+	leaves := make(map[string]bool)
+	for _, path := range leafPaths(tree, "") {
+		leaves[path] = true
+	}
+	for path := range p {
+		if !leaves[path] { // overwritten by a later file
+			delete(p, path)
+		}
+	}
+	return p
+}
+
+// leafPaths returns the paths of non-map values in the tree.
+func leafPaths(tree interface{}, path string) []string {
+	m, ok := tree.(map[string]interface{})
+	if !ok || len(m) == 0 {
+		if path == "" {
+			return nil
+		}
+		return []string{path}
+	}
+	var paths []string
+	for k, v := range m {
+		paths = append(paths, leafPaths(v, joinPath(path, k))...)
+	}
+	return paths
+}
+
+var yamlKeyPattern = regexp.MustCompile(`^("[^"]*"|'[^']*'|[^\s"'#{\[\-][^#]*?)\s*:(?:\s+(.*))?$`)
+
+// yamlKeyLines returns the line numbers of the first occurrences of keys in block mappings.
+// Template actions, flow collections and sequences are skipped.
+func yamlKeyLines(data []byte) map[string]int {
+	type frame struct {
+		indent int
+		path   string
+	}
+	lines := make(map[string]int)
+	var stack []frame
+	skip := -1 // skip lines indented more than this
+	for i, line := range strings.Split(string(data), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || trimmed == "---" {
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		if skip >= 0 {
+			if indent > skip {
+				continue
+			}
+			skip = -1
+		}
+		if trimmed == "-" || strings.HasPrefix(trimmed, "- ") {
+			skip = indent
+			continue
+		}
+		m := yamlKeyPattern.FindStringSubmatch(trimmed)
+		if m == nil {
+			continue
+		}
+		for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+			stack = stack[:len(stack)-1]
+		}
+		path := strings.Trim(m[1], `"'`)
+		if len(stack) > 0 {
+			path = joinPath(stack[len(stack)-1].path, path)
+		}
+		if _, ok := lines[path]; !ok {
+			lines[path] = i + 1
+		}
+		switch value := strings.TrimSpace(m[2]); {
+		case value == "" || strings.HasPrefix(value, "#"):
+			stack = append(stack, frame{indent: indent, path: path})
+		case strings.HasPrefix(value, "|") || strings.HasPrefix(value, ">"):
+			skip = indent
+		}
+	}
+	return lines
+}
+
+// jsonKeyLines returns the line numbers of the first occurrences of keys in objects.
+// Template actions between `left` and `right` are skipped, and keys in arrays are ignored.
+func jsonKeyLines(data []byte, left, right string) map[string]int {
+	type frame struct {
+		path   string
+		object bool
+		ignore bool
+	}
+	lines := make(map[string]int)
+	var stack []frame
+	key, line := "", 1
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		switch {
+		case c == '\n':
+			line++
+		case bytes.HasPrefix(data[i:], []byte(left)):
+			end := skipAction(data, i, left, right)
+			line += bytes.Count(data[i:end], []byte("\n"))
+			i = end - 1
+		case c == '"':
+			end := i + 1
+			for end < len(data) && data[end] != '"' && data[end] != '\n' {
+				switch {
+				case data[end] == '\\':
+					end += 2
+				case bytes.HasPrefix(data[end:], []byte(left)):
+					end = skipAction(data, end, left, right)
+				default:
+					end++
+				}
+			}
+			if end >= len(data) {
+				return lines
+			}
+			if data[end] != '"' {
+				i = end - 1
+				continue
+			}
+			var s string
+			err := json.Unmarshal(data[i:end+1], &s)
+			i = end
+			rest := bytes.TrimLeft(data[end+1:], " \t\r\n")
+			if err != nil || len(rest) == 0 || rest[0] != ':' || len(stack) == 0 {
+				continue
+			}
+			if top := stack[len(stack)-1]; top.object && !top.ignore {
+				key = joinPath(top.path, s)
+				if _, ok := lines[key]; !ok {
+					lines[key] = line
+				}
+			}
+		case c == '{' || c == '[':
+			f := frame{path: key, object: c == '{', ignore: c == '['}
+			if len(stack) == 0 {
+				f.path = ""
+			} else if top := stack[len(stack)-1]; !top.object || top.ignore {
+				f.ignore = true
+			}
+			stack = append(stack, f)
+		case c == '}' || c == ']':
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		}
+	}
+	return lines
+}
+
+// skipAction returns the index after the template action starting at data[i].
+func skipAction(data []byte, i int, left, right string) int {
+	end := bytes.Index(data[i+len(left):], []byte(right))
+	if end < 0 {
+		return len(data)
+	}
+	return i + len(left) + end + len(right)
+}
@@ -0,0 +1,112 @@
+package config_test
+
+import (
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/kayac/go-config"
+)
+
+var provenanceFS = fstest.MapFS{
+	"base.yaml": {Data: []byte(`# base
+domain: example.com
+is_dev: true
+db:
+  master: rw@/base
+  slave: ro@/example
+  timeout: 1s
+`)},
+	"production.yaml": {Data: []byte(`is_dev: false
+db:
+  master: {{ env "PROVENANCE_DB_USER" "rw" }}@/example
+{{ include "timeout.yaml" | nindent 2 }}
+`)},
+	"timeout.yaml": {Data: []byte(`timeout: 3s`)},
+	"local.json": {Data: []byte(`{
+  "domain": "local.example.com",
+  "db": {
+    "slave": "{{ env "PROVENANCE_SLAVE" "ro" }}@/local"
+  }
+}
+`)},
+	"local.toml": {Data: []byte(`domain = "local.example.com"
+
+[db]
+slave = "{{ env "PROVENANCE_SLAVE" "ro" }}@/local"
+`)},
+}
+
+func TestProvenance(t *testing.T) {
+	for _, local := range []string{"local.json", "local.toml"} {
+		t.Run(local, func(t *testing.T) {
+			loader := config.New()
+			loader.FS = provenanceFS
+			var conf Conf
+			prov, err := loader.LoadWithProvenance(&conf, "base.yaml", "production.yaml", local)
+			if err != nil {
+				t.Fatal(err)
+			}
+			expected := config.Provenance{
+				"domain":     {Source: local, Line: 1},
+				"is_dev":     {Source: "production.yaml", Line: 1},
+				"db.master":  {Source: "production.yaml", Line: 3, Template: true},
+				"db.slave":   {Source: local, Line: 4, Template: true},
+				"db.timeout": {Source: "production.yaml", Template: true},
+			}
+			if local == "local.json" {
+				expected["domain"] = config.Origin{Source: local, Line: 2}
+			}
+			if diff := cmp.Diff(expected, prov); diff != "" {
+				t.Errorf("unexpected provenance (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestProvenanceWithoutTemplate(t *testing.T) {
+	loader := config.New()
+	loader.FS = fstest.MapFS{
+		"a.yaml": {Data: []byte("domain: example.com\ndb:\n  master: rw@/example\nservers: [a, b]\n")},
+		"b.yaml": {Data: []byte("db: none\n")},
+	}
+	var conf map[string]interface{}
+	prov, err := loader.LoadAsWithProvenance("yaml", &conf, "a.yaml", "b.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := config.Provenance{
+		"domain":  {Source: "a.yaml", Line: 1},
+		"db":      {Source: "b.yaml", Line: 1},
+		"servers": {Source: "a.yaml", Line: 4},
+	}
+	if diff := cmp.Diff(expected, prov); diff != "" {
+		t.Errorf("unexpected provenance (-want +got):\n%s", diff)
+	}
+}
+
+func TestProvenanceConcurrent(t *testing.T) {
+	loader := config.New()
+	loader.FS = provenanceFS
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				var conf Conf
+				prov, err := loader.LoadWithProvenance(&conf, "base.yaml", "production.yaml")
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if o := prov["db.slave"]; o.Source != "base.yaml" || o.Line != 6 {
+					t.Errorf("unexpected origin: %#v", o)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,80 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// LoadReader loads YAML from `r`
+func LoadReader(conf interface{}, r io.Reader) error {
+	return defaultLoader.LoadReader(conf, r)
+}
+
+// LoadJSONReader loads JSON from `r`
+func LoadJSONReader(conf interface{}, r io.Reader) error {
+	return defaultLoader.LoadJSONReader(conf, r)
+}
+
+// LoadTOMLReader loads TOML from `r`
+func LoadTOMLReader(conf interface{}, r io.Reader) error {
+	return defaultLoader.LoadTOMLReader(conf, r)
+}
+
+// LoadWithEnvReader loads YAML from `r` with Env
+func LoadWithEnvReader(conf interface{}, r io.Reader) error {
+	return defaultLoader.LoadWithEnvReader(conf, r)
+}
+
+// LoadWithEnvJSONReader loads JSON from `r` with Env
+func LoadWithEnvJSONReader(conf interface{}, r io.Reader) error {
+	return defaultLoader.LoadWithEnvJSONReader(conf, r)
+}
+
+// LoadWithEnvTOMLReader loads TOML from `r` with Env
+func LoadWithEnvTOMLReader(conf interface{}, r io.Reader) error {
+	return defaultLoader.LoadWithEnvTOMLReader(conf, r)
+}
+
+// LoadReader loads YAML from `r`
+func (l *Loader) LoadReader(conf interface{}, r io.Reader) error {
+	return l.loadAsReader("yaml", conf, r, nil)
+}
+
+// LoadJSONReader loads JSON from `r`
+func (l *Loader) LoadJSONReader(conf interface{}, r io.Reader) error {
+	return l.loadAsReader("json", conf, r, nil)
+}
+
+// LoadTOMLReader loads TOML from `r`
+func (l *Loader) LoadTOMLReader(conf interface{}, r io.Reader) error {
+	return l.loadAsReader("toml", conf, r, nil)
+}
+
+// LoadWithEnvReader loads YAML from `r` with Env
+func (l *Loader) LoadWithEnvReader(conf interface{}, r io.Reader) error {
+	return l.loadAsReader("yaml", conf, r, l.replacer)
+}
+
+// LoadWithEnvJSONReader loads JSON from `r` with Env
+func (l *Loader) LoadWithEnvJSONReader(conf interface{}, r io.Reader) error {
+	return l.loadAsReader("json", conf, r, l.replacer)
+}
+
+// LoadWithEnvTOMLReader loads TOML from `r` with Env
+func (l *Loader) LoadWithEnvTOMLReader(conf interface{}, r io.Reader) error {
+	return l.loadAsReader("toml", conf, r, l.replacer)
+}
+
+// LoadAsReader loads from `r` with Env in the format `name`.
+func (l *Loader) LoadAsReader(name string, conf interface{}, r io.Reader) error {
+	return l.loadAsReader(name, conf, r, l.replacer)
+}
+
+func (l *Loader) loadAsReader(name string, conf interface{}, r io.Reader, custom customFunc) error {
+	src, err := ioutil.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("read failed: %w", err)
+	}
+	return l.loadAsBytes(name, conf, src, custom)
+}
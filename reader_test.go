@@ -0,0 +1,28 @@
+package config_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kayac/go-config"
+)
+
+func TestLoadWithEnvReader(t *testing.T) {
+	t.Setenv("READER_DOMAIN", "reader.example.com")
+	c := &Conf{}
+	r := strings.NewReader(`domain: '{{ env "READER_DOMAIN" }}'`)
+	if err := config.LoadWithEnvReader(c, r); err != nil {
+		t.Fatal(err)
+	}
+	if c.Domain != "reader.example.com" {
+		t.Errorf("unexpected domain: %s", c.Domain)
+	}
+
+	m := make(map[string]string)
+	if err := config.LoadJSONReader(&m, strings.NewReader(`{"foo": "bar"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if m["foo"] != "bar" {
+		t.Errorf("unexpected foo: %s", m["foo"])
+	}
+}
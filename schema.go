@@ -0,0 +1,421 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Schema represents a JSON Schema to validate rendered config documents.
+//
+// Supported keywords are $ref (in the same schema), type, enum, const,
+// properties, required, additionalProperties, patternProperties, items,
+// minItems, maxItems, minimum, maximum, exclusiveMinimum, exclusiveMaximum,
+// minLength, maxLength, pattern, allOf, anyOf, oneOf and not.
+// Other keywords are ignored.
+type Schema struct {
+	root interface{}
+
+	mu      sync.Mutex
+	regexps map[string]*regexp.Regexp
+}
+
+// ParseSchema parses a JSON Schema document in JSON.
+func ParseSchema(b []byte) (*Schema, error) {
+	var root interface{}
+	if err := json.Unmarshal(b, &root); err != nil {
+		return nil, fmt.Errorf("schema parse failed: %w", err)
+	}
+	return newSchema(root)
+}
+
+// LoadSchema loads a JSON Schema file in the format detected by its extension.
+func (l *Loader) LoadSchema(path string) (*Schema, error) {
+	b, err := readFile(l.FS, path)
+	if err != nil {
+		return nil, fmt.Errorf("%s read failed: %w", path, err)
+	}
+	c, err := l.codecOf(path)
+	if err != nil {
+		c = builtinCodecs["json"]
+	}
+	var root interface{}
+	if err := c.dec.Decode(b, &root); err != nil {
+		return nil, fmt.Errorf("%s schema parse failed: %w", path, err)
+	}
+	s, err := newSchema(normalize(root))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return s, nil
+}
+
+func newSchema(root interface{}) (*Schema, error) {
+	switch root.(type) {
+	case map[string]interface{}, bool:
+	default:
+		return nil, fmt.Errorf("schema must be an object or a boolean")
+	}
+	return &Schema{root: root, regexps: make(map[string]*regexp.Regexp)}, nil
+}
+
+// Validate validates the value `v`, which is a generic tree of maps, slices and scalars
+// (e.g. decoded into interface{}), and returns *ValidationError listing all violations.
+func (s *Schema) Validate(v interface{}) error {
+	return s.validate(v, nil)
+}
+
+func (s *Schema) validate(v interface{}, docs []document) error {
+	sv := &schemaValidator{schema: s, docs: docs}
+	sv.validate(s.root, v, nil)
+	if len(sv.errs) > 0 {
+		return &ValidationError{Errors: sv.errs}
+	}
+	return nil
+}
+
+type schemaValidator struct {
+	schema *Schema
+	docs   []document
+	errs   []*FieldError
+}
+
+func jsonPointer(keys []string) string {
+	r := strings.NewReplacer("~", "~0", "/", "~1")
+	var b strings.Builder
+	for _, k := range keys {
+		b.WriteString("/" + r.Replace(k))
+	}
+	return b.String()
+}
+
+func (sv *schemaValidator) add(keys []string, keyword string, format string, args ...interface{}) {
+	sv.errs = append(sv.errs, &FieldError{
+		Path:   jsonPointer(keys),
+		Rule:   keyword,
+		Source: sourceOf(sv.docs, keys),
+		Err:    fmt.Errorf(format, args...),
+	})
+}
+
+// valid reports whether v is valid against schema without recording errors.
+func (sv *schemaValidator) valid(schema, v interface{}, keys []string) bool {
+	sub := &schemaValidator{schema: sv.schema}
+	sub.validate(schema, v, keys)
+	return len(sub.errs) == 0
+}
+
+func (sv *schemaValidator) resolve(ref string) (interface{}, error) {
+	if !strings.HasPrefix(ref, "#") {
+		return nil, fmt.Errorf("unsupported $ref %s", ref)
+	}
+	cur := sv.schema.root
+	for _, p := range strings.Split(strings.TrimPrefix(ref, "#"), "/")[1:] {
+		p = strings.Replace(strings.Replace(p, "~1", "/", -1), "~0", "~", -1)
+		switch c := cur.(type) {
+		case map[string]interface{}:
+			cur = c[p]
+		case []interface{}:
+			i, err := strconv.Atoi(p)
+			if err != nil || i < 0 || i >= len(c) {
+				return nil, fmt.Errorf("invalid $ref %s", ref)
+			}
+			cur = c[i]
+		default:
+			return nil, fmt.Errorf("invalid $ref %s", ref)
+		}
+		if cur == nil {
+			return nil, fmt.Errorf("invalid $ref %s", ref)
+		}
+	}
+	return cur, nil
+}
+
+func (sv *schemaValidator) validate(schema, v interface{}, keys []string) {
+	if b, ok := schema.(bool); ok {
+		if !b {
+			sv.add(keys, "false", "no value is allowed")
+		}
+		return
+	}
+	s, ok := schema.(map[string]interface{})
+	if !ok {
+		return
+	}
+	if ref, ok := s["$ref"].(string); ok {
+		rs, err := sv.resolve(ref)
+		if err != nil {
+			sv.add(keys, "$ref", "%s", err)
+			return
+		}
+		sv.validate(rs, v, keys)
+	}
+
+	if t, ok := s["type"]; ok && !matchType(t, v) {
+		sv.add(keys, "type", "must be %s but got %s", typeNames(t), typeOf(v))
+		return
+	}
+	if enum, ok := s["enum"].([]interface{}); ok {
+		found := false
+		for _, e := range enum {
+			if equalValue(e, v) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			sv.add(keys, "enum", "must be one of %v but got %v", enum, v)
+		}
+	}
+	if c, ok := s["const"]; ok && !equalValue(c, v) {
+		sv.add(keys, "const", "must be %v but got %v", c, v)
+	}
+
+	switch v := v.(type) {
+	case map[string]interface{}:
+		sv.object(s, v, keys)
+	case []interface{}:
+		sv.array(s, v, keys)
+	case string:
+		sv.string(s, v, keys)
+	default:
+		if n, ok := toFloat(v); ok {
+			sv.number(s, n, keys)
+		}
+	}
+
+	if all, ok := s["allOf"].([]interface{}); ok {
+		for _, sub := range all {
+			sv.validate(sub, v, keys)
+		}
+	}
+	if anyOf, ok := s["anyOf"].([]interface{}); ok {
+		matched := false
+		for _, sub := range anyOf {
+			if sv.valid(sub, v, keys) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			sv.add(keys, "anyOf", "must be valid against any of schemas")
+		}
+	}
+	if oneOf, ok := s["oneOf"].([]interface{}); ok {
+		n := 0
+		for _, sub := range oneOf {
+			if sv.valid(sub, v, keys) {
+				n++
+			}
+		}
+		if n != 1 {
+			sv.add(keys, "oneOf", "must be valid against exactly one of schemas but %d", n)
+		}
+	}
+	if not, ok := s["not"]; ok && sv.valid(not, v, keys) {
+		sv.add(keys, "not", "must not be valid against the schema")
+	}
+}
+
+func (sv *schemaValidator) object(s map[string]interface{}, v map[string]interface{}, keys []string) {
+	if req, ok := s["required"].([]interface{}); ok {
+		for _, r := range req {
+			name, _ := r.(string)
+			if _, found := v[name]; !found {
+				sv.add(keys, "required", "property %q is required", name)
+			}
+		}
+	}
+	props, _ := s["properties"].(map[string]interface{})
+	patterns, _ := s["patternProperties"].(map[string]interface{})
+	names := make([]string, 0, len(v))
+	for k := range v {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		ck := append(keys[:len(keys):len(keys)], k)
+		matched := false
+		if ps, ok := props[k]; ok {
+			sv.validate(ps, v[k], ck)
+			matched = true
+		}
+		for p, ps := range patterns {
+			re, err := sv.schema.regexp(p)
+			if err != nil {
+				sv.add(keys, "patternProperties", "%s", err)
+				continue
+			}
+			if re.MatchString(k) {
+				sv.validate(ps, v[k], ck)
+				matched = true
+			}
+		}
+		if matched {
+			continue
+		}
+		if ap, ok := s["additionalProperties"]; ok {
+			if b, isBool := ap.(bool); isBool && !b {
+				sv.add(ck, "additionalProperties", "property %q is not allowed", k)
+			} else {
+				sv.validate(ap, v[k], ck)
+			}
+		}
+	}
+}
+
+func (sv *schemaValidator) array(s map[string]interface{}, v []interface{}, keys []string) {
+	if n, ok := toFloat(s["minItems"]); ok && float64(len(v)) < n {
+		sv.add(keys, "minItems", "must have at least %v items but got %d", n, len(v))
+	}
+	if n, ok := toFloat(s["maxItems"]); ok && float64(len(v)) > n {
+		sv.add(keys, "maxItems", "must have at most %v items but got %d", n, len(v))
+	}
+	switch items := s["items"].(type) {
+	case []interface{}:
+		for i, e := range v {
+			if i < len(items) {
+				sv.validate(items[i], e, append(keys[:len(keys):len(keys)], strconv.Itoa(i)))
+			}
+		}
+	case nil:
+	default:
+		for i, e := range v {
+			sv.validate(items, e, append(keys[:len(keys):len(keys)], strconv.Itoa(i)))
+		}
+	}
+}
+
+func (sv *schemaValidator) string(s map[string]interface{}, v string, keys []string) {
+	l := float64(utf8.RuneCountInString(v))
+	if n, ok := toFloat(s["minLength"]); ok && l < n {
+		sv.add(keys, "minLength", "length must be at least %v but got %v", n, l)
+	}
+	if n, ok := toFloat(s["maxLength"]); ok && l > n {
+		sv.add(keys, "maxLength", "length must be at most %v but got %v", n, l)
+	}
+	if p, ok := s["pattern"].(string); ok {
+		re, err := sv.schema.regexp(p)
+		if err != nil {
+			sv.add(keys, "pattern", "%s", err)
+		} else if !re.MatchString(v) {
+			sv.add(keys, "pattern", "must match %s but got %q", p, v)
+		}
+	}
+}
+
+func (sv *schemaValidator) number(s map[string]interface{}, v float64, keys []string) {
+	if n, ok := toFloat(s["minimum"]); ok && v < n {
+		sv.add(keys, "minimum", "must be at least %v but got %v", n, v)
+	}
+	if n, ok := toFloat(s["maximum"]); ok && v > n {
+		sv.add(keys, "maximum", "must be at most %v but got %v", n, v)
+	}
+	if n, ok := toFloat(s["exclusiveMinimum"]); ok && v <= n {
+		sv.add(keys, "exclusiveMinimum", "must be greater than %v but got %v", n, v)
+	}
+	if n, ok := toFloat(s["exclusiveMaximum"]); ok && v >= n {
+		sv.add(keys, "exclusiveMaximum", "must be less than %v but got %v", n, v)
+	}
+}
+
+func (s *Schema) regexp(p string) (*regexp.Regexp, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if re, ok := s.regexps[p]; ok {
+		return re, nil
+	}
+	re, err := regexp.Compile(p)
+	if err != nil {
+		return nil, err
+	}
+	s.regexps[p] = re
+	return re, nil
+}
+
+func typeOf(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string, time.Time:
+		return "string"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	default:
+		if n, ok := toFloat(v); ok {
+			if n == math.Trunc(n) {
+				return "integer"
+			}
+			return "number"
+		}
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func matchType(t, v interface{}) bool {
+	actual := typeOf(v)
+	var types []interface{}
+	switch t := t.(type) {
+	case []interface{}:
+		types = t
+	default:
+		types = []interface{}{t}
+	}
+	for _, t := range types {
+		if t == actual || (t == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+func typeNames(t interface{}) string {
+	if ts, ok := t.([]interface{}); ok {
+		names := make([]string, 0, len(ts))
+		for _, t := range ts {
+			names = append(names, fmt.Sprint(t))
+		}
+		return strings.Join(names, " or ")
+	}
+	return fmt.Sprint(t)
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	}
+	return 0, false
+}
+
+func equalValue(a, b interface{}) bool {
+	if fa, ok := toFloat(a); ok {
+		fb, ok := toFloat(b)
+		return ok && fa == fb
+	}
+	return reflect.DeepEqual(a, b)
+}
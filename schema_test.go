@@ -0,0 +1,87 @@
+package config_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/kayac/go-config"
+)
+
+var testSchema = []byte(`{
+  "type": "object",
+  "required": ["name", "db"],
+  "additionalProperties": false,
+  "properties": {
+    "name": {"type": "string", "minLength": 1},
+    "mode": {"enum": ["dev", "prod"]},
+    "db": {"$ref": "#/definitions/db"},
+    "replicas": {"type": "array", "items": {"$ref": "#/definitions/db"}}
+  },
+  "definitions": {
+    "db": {
+      "type": "object",
+      "required": ["host"],
+      "properties": {
+        "host": {"type": "string", "pattern": "^[a-z0-9.]+$"},
+        "port": {"type": "integer", "minimum": 1, "maximum": 65535}
+      }
+    }
+  }
+}`)
+
+func TestSchema(t *testing.T) {
+	schema, err := config.ParseSchema(testSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	base, err := genConfigFile("schema_base.yml", `
+name: app
+mode: dev
+db: {host: db.example.com, port: 3306}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	local, err := genConfigFile("schema_local.json", `{
+  "mode": "test",
+  "db": {"port": 0},
+  "replicas": [{"host": "r1.example.com", "port": 3306}, {"port": "3306"}],
+  "extra": true
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loader := config.New()
+	loader.Schema = schema
+	var ok map[string]interface{}
+	if err := loader.LoadFiles(&ok, base); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	var c map[string]interface{}
+	err = loader.LoadFiles(&c, base, local)
+	if err == nil {
+		t.Fatal("schema validation must fail")
+	}
+	t.Log(err)
+	var verr *config.ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("unexpected error type %T", err)
+	}
+	expected := []string{
+		"/db/port: minimum: must be at least 1 but got 0 (" + local + ")",
+		"/extra: additionalProperties: property \"extra\" is not allowed (" + local + ")",
+		"/mode: enum: must be one of [dev prod] but got test (" + local + ")",
+		"/replicas/1: required: property \"host\" is required (" + local + ")",
+		"/replicas/1/port: type: must be integer but got string (" + local + ")",
+	}
+	if len(verr.Errors) != len(expected) {
+		t.Fatalf("unexpected number of errors: %d", len(verr.Errors))
+	}
+	for i, fe := range verr.Errors {
+		if fe.Error() != expected[i] {
+			t.Errorf("unexpected error\n got: %s\nwant: %s", fe, expected[i])
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const durationPattern = `^([-+]?(([0-9]+(\.[0-9]*)?|\.[0-9]+)(ns|us|µs|ms|s|m|h))+|0)$`
+
+var timeType = reflect.TypeOf(time.Time{})
+
+var (
+	schemaTypesMu sync.Mutex
+	schemaTypes   = make(map[string]reflect.Type)
+)
+
+// RegisterSchemaType registers the type of `conf` by `name`,
+// to generate its JSON Schema by `merge-env-config schema NAME`.
+func RegisterSchemaType(name string, conf interface{}) {
+	schemaTypesMu.Lock()
+	defer schemaTypesMu.Unlock()
+	schemaTypes[name] = reflect.TypeOf(conf)
+}
+
+// SchemaTypes returns the names of registered types in sorted order.
+func SchemaTypes() []string {
+	schemaTypesMu.Lock()
+	defer schemaTypesMu.Unlock()
+	names := make([]string, 0, len(schemaTypes))
+	for name := range schemaTypes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GenerateRegisteredSchema generates a JSON Schema of the type registered by `name`.
+func GenerateRegisteredSchema(name, tag string) ([]byte, error) {
+	schemaTypesMu.Lock()
+	t, ok := schemaTypes[name]
+	schemaTypesMu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("type %s is not registered", name)
+	}
+	return generateSchema(t, tag)
+}
+
+// GenerateSchema generates a JSON Schema of the type of `conf`.
+// Keys are named by the struct tag `tag` ("yaml", "json" or "toml").
+//
+// time.Duration is a duration string (e.g. "1m30s"), and encoding.TextUnmarshaler is a string.
+// `validate` struct tags are mapped to required, minimum, maximum, minLength, maxLength,
+// minItems, maxItems and enum, and `default` struct tags are mapped to default.
+func GenerateSchema(conf interface{}, tag string) ([]byte, error) {
+	return generateSchema(reflect.TypeOf(conf), tag)
+}
+
+func generateSchema(t reflect.Type, tag string) ([]byte, error) {
+	g := &schemaGenerator{tag: tag, defs: make(map[string]interface{}), names: make(map[reflect.Type]string)}
+	s := g.schema(indirectType(t), true)
+	s["$schema"] = "http://json-schema.org/draft-07/schema#"
+	if len(g.defs) > 0 {
+		s["definitions"] = g.defs
+	}
+	return json.MarshalIndent(s, "", "  ")
+}
+
+type schemaGenerator struct {
+	tag   string
+	defs  map[string]interface{}
+	names map[reflect.Type]string
+}
+
+func (g *schemaGenerator) schema(t reflect.Type, root bool) map[string]interface{} {
+	if t == nil {
+		return map[string]interface{}{}
+	}
+	switch {
+	case t == durationType:
+		return map[string]interface{}{"type": "string", "pattern": durationPattern}
+	case t == timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case reflect.PtrTo(t).Implements(textUnmarshalerType):
+		return map[string]interface{}{"type": "string"}
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		return g.schema(t.Elem(), root)
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string"}
+		}
+		return map[string]interface{}{"type": "array", "items": g.schema(t.Elem(), false)}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": g.schema(t.Elem(), false)}
+	case reflect.Struct:
+		if root || t.Name() == "" {
+			return g.object(t)
+		}
+		return map[string]interface{}{"$ref": "#/definitions/" + g.define(t)}
+	}
+	return map[string]interface{}{}
+}
+
+// define adds the named struct type `t` into definitions and returns its name.
+func (g *schemaGenerator) define(t reflect.Type) string {
+	if name, ok := g.names[t]; ok {
+		return name
+	}
+	name := t.Name()
+	if _, exists := g.defs[name]; exists {
+		name = strings.Replace(t.PkgPath(), "/", ".", -1) + "." + t.Name()
+	}
+	g.names[t] = name
+	g.defs[name] = true // placeholder for recursive types
+	g.defs[name] = g.object(t)
+	return name
+}
+
+func (g *schemaGenerator) object(t reflect.Type) map[string]interface{} {
+	props := make(map[string]interface{})
+	var required []string
+	additional := false
+	g.fields(t, props, &required, &additional)
+	s := map[string]interface{}{
+		"type":                 "object",
+		"properties":           props,
+		"additionalProperties": additional,
+	}
+	if len(required) > 0 {
+		sort.Strings(required)
+		s["required"] = required
+	}
+	return s
+}
+
+func (g *schemaGenerator) fields(t reflect.Type, props map[string]interface{}, required *[]string, additional *bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" && !f.Anonymous { // unexported
+			continue
+		}
+		key, inline, ok := fieldKey(f, g.tag)
+		if !ok {
+			continue
+		}
+		if inline {
+			switch ft := indirectType(f.Type); ft.Kind() {
+			case reflect.Struct:
+				g.fields(ft, props, required, additional)
+			case reflect.Map:
+				*additional = true
+			}
+			continue
+		}
+		s := g.schema(f.Type, false)
+		if g.annotate(f, s) {
+			*required = append(*required, key)
+		}
+		props[key] = s
+	}
+}
+
+// annotate adds keywords by `validate` and `default` struct tags, and reports whether the field is required.
+func (g *schemaGenerator) annotate(f reflect.StructField, s map[string]interface{}) bool {
+	if _, isRef := s["$ref"]; isRef {
+		return strings.Contains(","+f.Tag.Get("validate")+",", ",required,")
+	}
+	ft := indirectType(f.Type)
+	if def, ok := f.Tag.Lookup("default"); ok {
+		v := reflect.New(ft).Elem()
+		if err := setValue(v, def); err == nil {
+			if ft == durationType || reflect.PtrTo(ft).Implements(textUnmarshalerType) {
+				s["default"] = def
+			} else {
+				s["default"] = v.Interface()
+			}
+		}
+	}
+	required := false
+	rules, _ := f.Tag.Lookup("validate")
+	for _, rule := range strings.Split(rules, ",") {
+		name, param := rule, ""
+		if i := strings.Index(rule, "="); i >= 0 {
+			name, param = rule[:i], rule[i+1:]
+		}
+		switch name {
+		case "required":
+			required = true
+		case "oneof":
+			var enum []interface{}
+			for _, o := range strings.Fields(param) {
+				v := reflect.New(ft).Elem()
+				if err := setValue(v, o); err == nil && ft != durationType {
+					enum = append(enum, v.Interface())
+				} else {
+					enum = append(enum, o)
+				}
+			}
+			s["enum"] = enum
+		case "min", "max", "len":
+			n, err := strconv.ParseFloat(param, 64)
+			if err != nil {
+				continue
+			}
+			var kws []string
+			switch s["type"] {
+			case "integer", "number":
+				kws = map[string][]string{"min": {"minimum"}, "max": {"maximum"}}[name]
+			case "string":
+				kws = map[string][]string{"min": {"minLength"}, "max": {"maxLength"}, "len": {"minLength", "maxLength"}}[name]
+			case "array":
+				kws = map[string][]string{"min": {"minItems"}, "max": {"maxItems"}, "len": {"minItems", "maxItems"}}[name]
+			}
+			for _, kw := range kws {
+				s[kw] = n
+			}
+		}
+	}
+	return required
+}
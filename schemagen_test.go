@@ -0,0 +1,93 @@
+package config_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/kayac/go-config"
+)
+
+type schemaDB struct {
+	Host    string        `yaml:"host" validate:"required"`
+	Port    int           `yaml:"port" default:"3306" validate:"min=1,max=65535"`
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+type schemaConf struct {
+	Name     string            `yaml:"name" validate:"required"`
+	Mode     string            `yaml:"mode" validate:"omitempty,oneof=dev prod"`
+	DB       schemaDB          `yaml:"db" validate:"required"`
+	Replicas []*schemaDB       `yaml:"replicas"`
+	Labels   map[string]string `yaml:"labels"`
+	Ignored  string            `yaml:"-"`
+}
+
+func TestGenerateSchema(t *testing.T) {
+	b, err := config.GenerateSchema(&schemaConf{}, "yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s map[string]interface{}
+	if err := json.Unmarshal(b, &s); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"$schema":              "http://json-schema.org/draft-07/schema#",
+		"type":                 "object",
+		"additionalProperties": false,
+		"required":             []interface{}{"db", "name"},
+		"properties": map[string]interface{}{
+			"name":     map[string]interface{}{"type": "string"},
+			"mode":     map[string]interface{}{"type": "string", "enum": []interface{}{"dev", "prod"}},
+			"db":       map[string]interface{}{"$ref": "#/definitions/schemaDB"},
+			"replicas": map[string]interface{}{"type": "array", "items": map[string]interface{}{"$ref": "#/definitions/schemaDB"}},
+			"labels":   map[string]interface{}{"type": "object", "additionalProperties": map[string]interface{}{"type": "string"}},
+		},
+		"definitions": map[string]interface{}{
+			"schemaDB": map[string]interface{}{
+				"type":                 "object",
+				"additionalProperties": false,
+				"required":             []interface{}{"host"},
+				"properties": map[string]interface{}{
+					"host":    map[string]interface{}{"type": "string"},
+					"port":    map[string]interface{}{"type": "integer", "default": float64(3306), "minimum": float64(1), "maximum": float64(65535)},
+					"timeout": map[string]interface{}{"type": "string", "pattern": `^([-+]?(([0-9]+(\.[0-9]*)?|\.[0-9]+)(ns|us|µs|ms|s|m|h))+|0)$`},
+				},
+			},
+		},
+	}
+	if diff := cmp.Diff(expected, s); diff != "" {
+		t.Errorf("unexpected schema: %s", diff)
+	}
+
+	schema, err := config.ParseSchema(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loader := config.New()
+	loader.Schema = schema
+	var c schemaConf
+	if err := loader.LoadBytes(&c, []byte("name: app\ndb: {host: localhost, timeout: 1m30s}\n")); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if err := loader.LoadBytes(&c, []byte("name: app\ndb: {host: localhost, timeout: 90}\nmode: test\n")); err == nil {
+		t.Error("validation must fail")
+	} else {
+		t.Log(err)
+	}
+}
+
+func TestRegisterSchemaType(t *testing.T) {
+	config.RegisterSchemaType("test", schemaConf{})
+	if diff := cmp.Diff([]string{"test"}, config.SchemaTypes()); diff != "" {
+		t.Errorf("unexpected types: %s", diff)
+	}
+	if _, err := config.GenerateRegisteredSchema("test", "json"); err != nil {
+		t.Error(err)
+	}
+	if _, err := config.GenerateRegisteredSchema("unknown", "json"); err == nil {
+		t.Error("unknown type must be an error")
+	}
+}
@@ -0,0 +1,327 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+)
+
+// UnknownKey is a key in a config document which doesn't match any struct field.
+type UnknownKey struct {
+	Key    string // dotted path of the key, or the key name only for YAML
+	Source string // the file which has the key, empty for bytes and readers
+	Line   int    // line number in the rendered document, 0 if unknown
+}
+
+func (k UnknownKey) String() string {
+	var b strings.Builder
+	if k.Source != "" {
+		b.WriteString(k.Source + ":")
+	}
+	if k.Line > 0 {
+		b.WriteString(strconv.Itoa(k.Line) + ":")
+	}
+	if b.Len() > 0 {
+		b.WriteString(" ")
+	}
+	b.WriteString(k.Key)
+	return b.String()
+}
+
+// UnknownKeysError is returned in strict mode when config documents have unknown keys.
+type UnknownKeysError struct {
+	Keys []UnknownKey
+}
+
+func (e *UnknownKeysError) Error() string {
+	msgs := make([]string, 0, len(e.Keys))
+	for _, k := range e.Keys {
+		msgs = append(msgs, k.String())
+	}
+	return "unknown keys:\n  " + strings.Join(msgs, "\n  ")
+}
+
+// checkUnknownKeys reports all keys in `docs` which don't match any field of `conf`
+// by the struct tag `tag` of the format which decodes the merged documents.
+// Only struct pointers are checked.
+func checkUnknownKeys(conf interface{}, docs []document, tag string) error {
+	if !isStructPtr(conf) {
+		return nil
+	}
+	t := reflect.TypeOf(conf).Elem()
+	var keys []UnknownKey
+	for _, doc := range docs {
+		var found []UnknownKey
+		if doc.codec.unknownKeys != nil && doc.codec.name == tag {
+			found = doc.codec.unknownKeys(doc.data, t)
+		} else {
+			found = treeUnknownKeys(doc.tree, t, tag, "")
+			lines := renderedKeyLines(doc)
+			for i := range found {
+				found[i].Line = lines[found[i].Key]
+			}
+		}
+		for _, k := range found {
+			k.Source = doc.name
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) > 0 {
+		return &UnknownKeysError{Keys: keys}
+	}
+	return nil
+}
+
+// renderedKeyLines returns line numbers of keys in the rendered `doc`, if its format is known.
+func renderedKeyLines(doc document) map[string]int {
+	switch doc.codec.name {
+	case "yaml":
+		return yamlKeyLines(doc.data)
+	case "json":
+		return jsonKeyLines(doc.data, "{{", "}}")
+	case "toml":
+		return tomlKeyLines(doc.data)
+	}
+	return nil
+}
+
+var yamlUnknownField = regexp.MustCompile(`^line (\d+): field (.+) not found in type `)
+
+// yamlUnknownKeys finds unknown keys by yaml.UnmarshalStrict, which reports the key names only.
+func yamlUnknownKeys(data []byte, t reflect.Type) []UnknownKey {
+	err := yaml.UnmarshalStrict(data, reflect.New(t).Interface())
+	te, ok := err.(*yaml.TypeError)
+	if !ok {
+		return nil // other errors are reported by decoding
+	}
+	var keys []UnknownKey
+	for _, msg := range te.Errors {
+		m := yamlUnknownField.FindStringSubmatch(msg)
+		if m == nil {
+			continue
+		}
+		line, _ := strconv.Atoi(m[1])
+		keys = append(keys, UnknownKey{Key: m[2], Line: line})
+	}
+	return keys
+}
+
+// jsonUnknownKeys finds unknown keys by walking tokens, because
+// json.Decoder.DisallowUnknownFields stops at the first unknown key.
+func jsonUnknownKeys(data []byte, t reflect.Type) []UnknownKey {
+	w := &jsonWalker{data: data, dec: json.NewDecoder(bytes.NewReader(data))}
+	w.dec.UseNumber()
+	w.value(t, "")
+	return w.keys
+}
+
+type jsonWalker struct {
+	data []byte
+	dec  *json.Decoder
+	keys []UnknownKey
+}
+
+// value reads a value of the type `t` at `path`, and reports whether it was read successfully.
+// `t` is nil when the value is ignored.
+func (w *jsonWalker) value(t reflect.Type, path string) bool {
+	tok, err := w.dec.Token()
+	if err != nil {
+		return false // syntax errors are reported by decoding
+	}
+	switch tok {
+	case json.Delim('{'):
+		for w.dec.More() {
+			tok, err := w.dec.Token()
+			if err != nil {
+				return false
+			}
+			key, _ := tok.(string)
+			kp := joinPath(path, key)
+			ft, known := childType(t, key, "json")
+			if !known {
+				w.keys = append(w.keys, UnknownKey{Key: kp, Line: w.line()})
+			}
+			if !w.value(ft, kp) {
+				return false
+			}
+		}
+	case json.Delim('['):
+		et := elemType(t)
+		for i := 0; w.dec.More(); i++ {
+			if !w.value(et, fmt.Sprintf("%s[%d]", path, i)) {
+				return false
+			}
+		}
+	default:
+		return true
+	}
+	_, err = w.dec.Token() // closing delimiter
+	return err == nil
+}
+
+// line returns the line number of the last read token.
+func (w *jsonWalker) line() int {
+	return bytes.Count(w.data[:w.dec.InputOffset()], []byte("\n")) + 1
+}
+
+// tomlUnknownKeys finds unknown keys by MetaData.Undecoded of BurntSushi/toml.
+func tomlUnknownKeys(data []byte, t reflect.Type) []UnknownKey {
+	md, err := toml.Decode(string(data), reflect.New(t).Interface())
+	if err != nil {
+		return nil // reported by decoding
+	}
+	undecoded := make(map[string]bool)
+	for _, k := range md.Undecoded() {
+		undecoded[k.String()] = true
+	}
+	lines := tomlKeyLines(data)
+	var keys []UnknownKey
+	for _, k := range md.Undecoded() {
+		if len(k) > 1 && undecoded[k[:len(k)-1].String()] {
+			continue // in an unknown table
+		}
+		keys = append(keys, UnknownKey{Key: k.String(), Line: lines[k.String()]})
+	}
+	return keys
+}
+
+// tomlKeyLines returns the line numbers of the first occurrences of keys and table headers.
+// Quoted keys which contain "." or "=" are not supported.
+func tomlKeyLines(data []byte) map[string]int {
+	lines := make(map[string]int)
+	add := func(key string, n int) {
+		if _, ok := lines[key]; !ok {
+			lines[key] = n
+		}
+	}
+	table := ""
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+		case strings.HasPrefix(line, "["):
+			end := strings.LastIndex(line, "]")
+			if end < 0 {
+				continue
+			}
+			table = tomlKey(strings.Trim(line[:end], "[]"))
+			add(table, i+1)
+		default:
+			eq := strings.Index(line, "=")
+			if eq < 0 {
+				continue
+			}
+			key := tomlKey(line[:eq])
+			if table != "" {
+				key = table + "." + key
+			}
+			add(key, i+1)
+		}
+	}
+	return lines
+}
+
+func tomlKey(s string) string {
+	parts := strings.Split(s, ".")
+	for i, p := range parts {
+		parts[i] = strings.Trim(strings.TrimSpace(p), `"'`)
+	}
+	return toml.Key(parts).String()
+}
+
+// treeUnknownKeys finds unknown keys in the generic tree of a registered format.
+func treeUnknownKeys(tree interface{}, t reflect.Type, tag, path string) []UnknownKey {
+	var keys []UnknownKey
+	switch v := tree.(type) {
+	case map[string]interface{}:
+		names := make([]string, 0, len(v))
+		for k := range v {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+		for _, k := range names {
+			ft, known := childType(t, k, tag)
+			if !known {
+				keys = append(keys, UnknownKey{Key: joinPath(path, k)})
+			}
+			keys = append(keys, treeUnknownKeys(v[k], ft, tag, joinPath(path, k))...)
+		}
+	case []interface{}:
+		et := elemType(t)
+		for i, e := range v {
+			keys = append(keys, treeUnknownKeys(e, et, tag, fmt.Sprintf("%s[%d]", path, i))...)
+		}
+	}
+	return keys
+}
+
+// childType returns the type of the value of `key` in a value of the type `t`,
+// and reports whether the key is known. Keys are matched case-insensitively.
+// The returned type is nil when values under the key are not checked.
+func childType(t reflect.Type, key, tag string) (reflect.Type, bool) {
+	if t == nil {
+		return nil, true
+	}
+	t = indirectType(t)
+	switch {
+	case t == durationType || t == timeType || reflect.PtrTo(t).Implements(textUnmarshalerType):
+		return nil, true
+	case t.Kind() == reflect.Map:
+		return t.Elem(), true
+	case t.Kind() == reflect.Struct:
+		return structFieldType(t, key, tag)
+	}
+	return nil, true
+}
+
+// structFieldType returns the type of the field of `key` in the struct type `t`,
+// looking into inline structs. Inline maps accept any keys.
+func structFieldType(t reflect.Type, key, tag string) (reflect.Type, bool) {
+	var rest reflect.Type
+	hasRest := false
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" && !f.Anonymous { // unexported
+			continue
+		}
+		name, inline, ok := fieldKey(f, tag)
+		if !ok {
+			continue
+		}
+		if !inline {
+			if strings.EqualFold(name, key) {
+				return f.Type, true
+			}
+			continue
+		}
+		switch ft := indirectType(f.Type); ft.Kind() {
+		case reflect.Struct:
+			if et, ok := structFieldType(ft, key, tag); ok {
+				return et, true
+			}
+		case reflect.Map:
+			rest, hasRest = ft.Elem(), true
+		}
+	}
+	return rest, hasRest
+}
+
+// elemType returns the element type of slices and arrays, or nil for other types.
+func elemType(t reflect.Type) reflect.Type {
+	if t == nil {
+		return nil
+	}
+	switch t = indirectType(t); t.Kind() {
+	case reflect.Slice, reflect.Array:
+		return t.Elem()
+	}
+	return nil
+}
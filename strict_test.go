@@ -0,0 +1,184 @@
+package config_test
+
+import (
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/kayac/go-config"
+)
+
+type strictConf struct {
+	Name    string            `yaml:"name" json:"name" toml:"name"`
+	DB      DBConfig          `yaml:"db" json:"db" toml:"db"`
+	Servers []strictServer    `yaml:"servers" json:"servers" toml:"servers"`
+	Labels  map[string]string `yaml:"labels" json:"labels" toml:"labels"`
+	Extra   interface{}       `yaml:"extra" json:"extra" toml:"extra"`
+}
+
+type strictServer struct {
+	Host string `yaml:"host" json:"host" toml:"host"`
+}
+
+var strictTests = []struct {
+	name string
+	file string
+	src  string
+	keys []config.UnknownKey
+}{
+	{
+		name: "yaml",
+		file: "strict.yml",
+		src: `name: app
+tiemout: 1s
+db:
+  master: rw@/example
+  tiemout: 1s
+servers:
+  - host: a.example.com
+    port: 80
+labels: {a: b}
+extra: {anything: goes}
+`,
+		keys: []config.UnknownKey{
+			{Key: "tiemout", Line: 2},
+			{Key: "tiemout", Line: 5},
+			{Key: "port", Line: 8},
+		},
+	},
+	{
+		name: "json",
+		file: "strict.json",
+		src: `{
+  "name": "app",
+  "tiemout": "1s",
+  "db": {"master": "rw@/example", "tiemout": "1s"},
+  "servers": [
+    {"host": "a.example.com", "port": 80}
+  ],
+  "labels": {"a": "b"},
+  "extra": {"anything": "goes"}
+}
+`,
+		keys: []config.UnknownKey{
+			{Key: "tiemout", Line: 3},
+			{Key: "db.tiemout", Line: 4},
+			{Key: "servers[0].port", Line: 6},
+		},
+	},
+	{
+		name: "toml",
+		file: "strict.toml",
+		src: `name = "app"
+tiemout = "1s"
+
+[db]
+master = "rw@/example"
+tiemout = "1s"
+
+[unknown]
+a = 1
+
+[labels]
+a = "b"
+`,
+		keys: []config.UnknownKey{
+			{Key: "tiemout", Line: 2},
+			{Key: "db.tiemout", Line: 6},
+			{Key: "unknown", Line: 8},
+		},
+	},
+}
+
+func TestStrict(t *testing.T) {
+	for _, tt := range strictTests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, err := genConfigFile(tt.file, tt.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			loader := config.New()
+			var lenient strictConf
+			if err := loader.LoadFiles(&lenient, path); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			loader.Strict = true
+			var conf strictConf
+			err = loader.LoadFiles(&conf, path)
+			var uerr *config.UnknownKeysError
+			if !errors.As(err, &uerr) {
+				t.Fatalf("expected UnknownKeysError, got %v", err)
+			}
+			for i := range tt.keys {
+				tt.keys[i].Source = path
+			}
+			if diff := cmp.Diff(tt.keys, uerr.Keys); diff != "" {
+				t.Errorf("unexpected keys (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestStrictMultipleFiles(t *testing.T) {
+	base, err := genConfigFile("strict_base.yml", "name: app\nnmae: typo\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	local, err := genConfigFile("strict_local.json", "{\n  \"db\": {\"slaev\": \"ro@/example\"}\n}\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	loader := config.New()
+	loader.Strict = true
+	var conf strictConf
+	err = loader.LoadFiles(&conf, base, local)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	want := "unknown keys:\n  " + base + ":2: nmae\n  " + local + ":2: db.slaev"
+	if err.Error() != want {
+		t.Errorf("unexpected error: %q, want %q", err.Error(), want)
+	}
+
+	if err := loader.LoadBytes(&conf, []byte("name: app\n")); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	err = loader.LoadBytes(&conf, []byte("name: app\nnmae: typo\n"))
+	if err == nil || err.Error() != "unknown keys:\n  2: nmae" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+type strictYAMLConf struct {
+	Name  string `yaml:"name"`
+	IsDev bool   `yaml:"is_dev"`
+}
+
+func TestStrictMixedFormats(t *testing.T) {
+	loader := config.New()
+	loader.FS = fstest.MapFS{
+		"base.yaml":  {Data: []byte("name: app\n")},
+		"local.json": {Data: []byte("{\n  \"is_dev\": true,\n  \"isdev\": true\n}\n")},
+	}
+	loader.Strict = true
+	var conf strictYAMLConf
+	err := loader.LoadFiles(&conf, "base.yaml", "local.json")
+	var uerr *config.UnknownKeysError
+	if !errors.As(err, &uerr) {
+		t.Fatalf("expected UnknownKeysError, got %v", err)
+	}
+	expected := []config.UnknownKey{{Key: "isdev", Source: "local.json", Line: 3}}
+	if diff := cmp.Diff(expected, uerr.Keys); diff != "" {
+		t.Errorf("unexpected keys (-want +got):\n%s", diff)
+	}
+
+	loader.FS.(fstest.MapFS)["local.json"] = &fstest.MapFile{Data: []byte(`{"is_dev": true}`)}
+	if err := loader.LoadFiles(&conf, "base.yaml", "local.json"); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(strictYAMLConf{Name: "app", IsDev: true}, conf); diff != "" {
+		t.Errorf("unexpected config (-want +got):\n%s", diff)
+	}
+}
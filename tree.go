@@ -0,0 +1,298 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// yamlScalar is a non-string YAML scalar in a tree, which keeps its text in the document
+// to be decoded from merged documents as it is decoded from the original document.
+// e.g. `1.10` is decoded into a string field as "1.10", not "1.1".
+type yamlScalar struct {
+	text  string
+	value interface{}
+}
+
+func (s yamlScalar) String() string {
+	return fmt.Sprint(s.value)
+}
+
+// yamlNode decodes a YAML node into a tree with yamlScalar.
+type yamlNode struct {
+	v interface{}
+}
+
+var yamlTimestampPrefix = regexp.MustCompile(`^[0-9]{4}-[0-9]{1,2}-[0-9]{1,2}`)
+
+func (n *yamlNode) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var v interface{}
+	if err := unmarshal(&v); err != nil {
+		return err
+	}
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		var m map[yamlKey]yamlNode
+		if err := unmarshal(&m); err != nil {
+			return err
+		}
+		t := make(map[string]interface{}, len(m))
+		for k, e := range m {
+			t[k.s] = e.v
+		}
+		n.v = t
+	case []interface{}:
+		var s []yamlNode
+		if err := unmarshal(&s); err != nil {
+			return err
+		}
+		t := make([]interface{}, len(s))
+		for i, e := range s {
+			t[i] = e.v
+		}
+		n.v = t
+	case string:
+		n.v = v
+		// plain timestamps are decoded as strings, but can be decoded into time.Time
+		var t time.Time
+		if yamlTimestampPrefix.MatchString(v) && unmarshal(&t) == nil {
+			n.v = yamlScalar{text: v, value: v}
+		}
+	case nil:
+	default:
+		var text string
+		if err := unmarshal(&text); err != nil {
+			return err
+		}
+		n.v = yamlScalar{text: text, value: v}
+	}
+	return nil
+}
+
+// yamlKey decodes a key of YAML mappings into a string.
+type yamlKey struct {
+	s string
+}
+
+func (k *yamlKey) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var v interface{}
+	if err := unmarshal(&v); err != nil {
+		return err
+	}
+	k.s = fmt.Sprint(v)
+	return nil
+}
+
+// decodeYAMLTree decodes a YAML document into a tree.
+func decodeYAMLTree(data []byte) (interface{}, error) {
+	var n yamlNode
+	if err := yaml.Unmarshal(data, &n); err != nil {
+		return nil, err
+	}
+	return n.v, nil
+}
+
+// resolveTree returns a copy of the tree `v` whose yamlScalar values are replaced by their values.
+func resolveTree(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[k] = resolveTree(e)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(v))
+		for i, e := range v {
+			s[i] = resolveTree(e)
+		}
+		return s
+	case yamlScalar:
+		return v.value
+	}
+	return v
+}
+
+// yamlEmitter encodes a tree into a YAML document in the block style,
+// and records the keys of the value on each line to locate decode errors.
+// Values in slices are located by the keys of the slice.
+type yamlEmitter struct {
+	buf   bytes.Buffer
+	lines [][]string
+}
+
+// encodeYAMLTree encodes the tree `v` into a YAML document,
+// and returns it with the keys of the value on each line.
+func encodeYAMLTree(v interface{}) ([]byte, [][]string) {
+	e := &yamlEmitter{}
+	e.value(v, 0, nil, false)
+	e.buf.WriteByte('\n')
+	return e.buf.Bytes(), e.lines
+}
+
+func (e *yamlEmitter) newline(indent int, keys []string) {
+	if len(e.lines) > 0 {
+		e.buf.WriteByte('\n')
+	}
+	e.lines = append(e.lines, keys)
+	e.buf.WriteString(strings.Repeat(" ", indent))
+}
+
+func (e *yamlEmitter) value(v interface{}, indent int, keys []string, inSlice bool) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if len(v) == 0 {
+			e.scalar("{}", keys)
+			return
+		}
+		names := make([]string, 0, len(v))
+		for k := range v {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+		for _, k := range names {
+			ck := keys
+			if !inSlice {
+				ck = append(keys[:len(keys):len(keys)], k)
+			}
+			e.newline(indent, ck)
+			e.buf.WriteString(yamlKeyText(k) + ":")
+			e.value(v[k], indent+2, ck, inSlice)
+		}
+	case []interface{}:
+		if len(v) == 0 {
+			e.scalar("[]", keys)
+			return
+		}
+		for _, x := range v {
+			e.newline(indent, keys)
+			e.buf.WriteString("-")
+			e.value(x, indent+2, keys, true)
+		}
+	default:
+		e.scalar(yamlScalarText(v), keys)
+	}
+}
+
+func (e *yamlEmitter) scalar(text string, keys []string) {
+	if len(e.lines) == 0 { // the root
+		e.lines = append(e.lines, keys)
+	}
+	e.buf.WriteString(" " + text)
+}
+
+var (
+	yamlPlainKey = regexp.MustCompile(`^[A-Za-z0-9_.+-]+$`)
+	yamlNullKeys = map[string]bool{"null": true, "Null": true, "NULL": true}
+)
+
+// yamlKeyText returns `k` as a YAML key. Keys which look like numbers or booleans are
+// not quoted, to be decoded into maps of such keys.
+func yamlKeyText(k string) string {
+	if yamlPlainKey.MatchString(k) && !yamlNullKeys[k] {
+		return k
+	}
+	return yamlQuote(k)
+}
+
+func yamlScalarText(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case yamlScalar:
+		return v.text
+	case json.Number:
+		return string(v)
+	case string:
+		return yamlQuote(v)
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		switch {
+		case math.IsInf(v, 1):
+			return ".inf"
+		case math.IsInf(v, -1):
+			return "-.inf"
+		case math.IsNaN(v):
+			return ".nan"
+		}
+		s := strconv.FormatFloat(v, 'g', -1, 64)
+		if !strings.ContainsAny(s, ".eEn") {
+			s += ".0"
+		}
+		return s
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprint(v)
+	}
+	b, err := yaml.Marshal(v)
+	if err != nil {
+		return yamlQuote(fmt.Sprint(v))
+	}
+	return strings.TrimSuffix(string(b), "\n")
+}
+
+// yamlQuote quotes `s` as a double-quoted YAML scalar, whose escapes are compatible with JSON.
+func yamlQuote(s string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+var yamlErrorLine = regexp.MustCompile(`^line ([0-9]+): (.*)$`)
+
+// locateDecodeError rewrites errors of decoding the merged documents
+// to have the origins of the values instead of positions in the merged document.
+// `lines` has the keys on each line of the merged YAML document.
+func (l *Loader) locateDecodeError(err error, docs []document, tree interface{}, lines [][]string) error {
+	var prov Provenance
+	// origin returns the origin of the value at `keys` followed by its path
+	origin := func(keys []string) string {
+		if prov == nil {
+			prov = l.provenance(docs, tree)
+		}
+		path := strings.Join(keys, ".")
+		o, ok := prov[path]
+		if !ok {
+			o.Source = sourceOf(docs, keys)
+		}
+		switch {
+		case o.Source != "" && o.Line > 0:
+			return fmt.Sprintf("%s:%d: %s", o.Source, o.Line, path)
+		case o.Source != "":
+			return o.Source + ": " + path
+		}
+		return path
+	}
+
+	var te *yaml.TypeError
+	if errors.As(err, &te) && lines != nil {
+		located := &yaml.TypeError{Errors: make([]string, len(te.Errors))}
+		for i, msg := range te.Errors {
+			located.Errors[i] = msg
+			m := yamlErrorLine.FindStringSubmatch(msg)
+			if m == nil {
+				continue
+			}
+			if n, _ := strconv.Atoi(m[1]); n >= 1 && n <= len(lines) && len(lines[n-1]) > 0 {
+				located.Errors[i] = origin(lines[n-1]) + ": " + m[2]
+			}
+		}
+		return located
+	}
+	var ue *json.UnmarshalTypeError
+	if errors.As(err, &ue) && ue.Field != "" {
+		return fmt.Errorf("%s: %w", origin(strings.Split(ue.Field, ".")), err)
+	}
+	return err
+}
@@ -0,0 +1,285 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Validator is the interface implemented by config structs which validate themselves.
+// Validate is called after loading, for the root struct and nested structs.
+type Validator interface {
+	Validate() error
+}
+
+// FieldError represents an invalid value of a config field.
+type FieldError struct {
+	Path string // path of config keys (e.g. "db.servers[0].port"), or JSON pointer for Schema
+
+	Rule   string // failed rule in the `validate` struct tag, empty for Validator
+	Source string // the file which set the value, if known
+	Err    error
+}
+
+func (e *FieldError) Error() string {
+	var b strings.Builder
+	if e.Path != "" {
+		b.WriteString(e.Path + ": ")
+	}
+	if e.Rule != "" {
+		b.WriteString(e.Rule + ": ")
+	}
+	b.WriteString(e.Err.Error())
+	if e.Source != "" {
+		b.WriteString(" (" + e.Source + ")")
+	}
+	return b.String()
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationError is returned when loaded config values are invalid.
+type ValidationError struct {
+	Errors []*FieldError
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, fe := range e.Errors {
+		msgs = append(msgs, fe.Error())
+	}
+	return "validation failed:\n  " + strings.Join(msgs, "\n  ")
+}
+
+// Validate validates `conf` by `validate` struct tags and Validator.
+// Field paths in errors are named by `yaml` struct tags.
+// Loader validates loaded values if Loader.Validate is true.
+func Validate(conf interface{}) error {
+	return validate(conf, nil, "yaml")
+}
+
+// validate validates `conf` loaded from `docs` in the format whose struct tag is `tag`.
+//
+// Supported rules of `validate` struct tags are:
+//
+//	required      the value must not be zero (nor empty for strings, slices and maps)
+//	omitempty     skip other rules if the value is zero
+//	min=N, max=N  bounds of numbers and durations, or lengths of strings, slices and maps
+//	len=N         length of strings, slices and maps
+//	oneof=a b c   the value must be one of space separated values
+//
+// Other rules are ignored, to share tags with other validation libraries.
+// Rules except required are skipped for nil pointers.
+func validate(conf interface{}, docs []document, tag string) error {
+	v := &validator{docs: docs, tag: tag}
+	v.value(reflect.ValueOf(conf), "", nil)
+	if len(v.errs) > 0 {
+		return &ValidationError{Errors: v.errs}
+	}
+	return nil
+}
+
+type validator struct {
+	docs []document
+	tag  string
+	errs []*FieldError
+}
+
+func (v *validator) add(path string, keys []string, rule string, err error) {
+	v.errs = append(v.errs, &FieldError{
+		Path:   path,
+		Rule:   rule,
+		Source: sourceOf(v.docs, keys),
+		Err:    err,
+	})
+}
+
+func (v *validator) value(rv reflect.Value, path string, keys []string) {
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !rv.IsNil() {
+			v.value(rv.Elem(), path, keys)
+		}
+	case reflect.Struct:
+		if rv.Type() == durationType {
+			return
+		}
+		v.structFields(rv, path, keys)
+		v.validateSelf(rv, path, keys)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			v.value(rv.Index(i), fmt.Sprintf("%s[%d]", path, i), keys)
+		}
+	case reflect.Map:
+		iter := rv.MapRange()
+		for iter.Next() {
+			k := fmt.Sprint(iter.Key().Interface())
+			v.value(iter.Value(), joinPath(path, k), append(keys[:len(keys):len(keys)], k))
+		}
+	}
+}
+
+func (v *validator) validateSelf(rv reflect.Value, path string, keys []string) {
+	var i interface{}
+	if rv.CanAddr() {
+		i = rv.Addr().Interface()
+	} else {
+		i = rv.Interface()
+	}
+	if val, ok := i.(Validator); ok {
+		if err := val.Validate(); err != nil {
+			v.add(path, keys, "", err)
+		}
+	}
+}
+
+func (v *validator) structFields(rv reflect.Value, path string, keys []string) {
+	t := rv.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" && !f.Anonymous { // unexported
+			continue
+		}
+		key, inline, ok := fieldKey(f, v.tag)
+		if !ok {
+			continue
+		}
+		fp, fk := path, keys
+		if !inline {
+			fp, fk = joinPath(path, key), append(keys[:len(keys):len(keys)], key)
+		}
+		fv := rv.Field(i)
+		if rules, ok := f.Tag.Lookup("validate"); ok {
+			if !v.rules(fv, rules, fp, fk) {
+				continue
+			}
+		}
+		v.value(fv, fp, fk)
+	}
+}
+
+// rules validates `fv` by `rules`, and reports whether fv is valid.
+func (v *validator) rules(fv reflect.Value, rules, path string, keys []string) bool {
+	valid := true
+	for _, rule := range strings.Split(rules, ",") {
+		name, param := rule, ""
+		if i := strings.Index(rule, "="); i >= 0 {
+			name, param = rule[:i], rule[i+1:]
+		}
+		if name == "omitempty" {
+			if isEmpty(fv) {
+				return true
+			}
+			continue
+		}
+		if err := checkRule(fv, name, param); err != nil {
+			v.add(path, keys, rule, err)
+			valid = false
+		}
+	}
+	return valid
+}
+
+func checkRule(fv reflect.Value, name, param string) error {
+	if name == "required" {
+		if isEmpty(fv) {
+			return fmt.Errorf("must not be empty")
+		}
+		return nil
+	}
+	if fv = reflect.Indirect(fv); !fv.IsValid() {
+		return nil
+	}
+	switch name {
+	case "min", "max":
+		return checkBound(fv, name, param)
+	case "len":
+		n, err := strconv.Atoi(param)
+		if err != nil {
+			return fmt.Errorf("invalid parameter: %w", err)
+		}
+		if l, ok := length(fv); !ok {
+			return fmt.Errorf("unsupported type %s", fv.Type())
+		} else if l != n {
+			return fmt.Errorf("length must be %d but got %d", n, l)
+		}
+	case "oneof":
+		s := fmt.Sprint(fv.Interface())
+		for _, o := range strings.Fields(param) {
+			if s == o {
+				return nil
+			}
+		}
+		return fmt.Errorf("must be one of [%s] but got %q", param, s)
+	}
+	return nil
+}
+
+func checkBound(fv reflect.Value, name, param string) error {
+	fv = reflect.Indirect(fv)
+	if !fv.IsValid() {
+		return nil
+	}
+	var value, bound float64
+	var err error
+	switch {
+	case fv.Type() == durationType:
+		var d time.Duration
+		d, err = time.ParseDuration(param)
+		value, bound = float64(fv.Int()), float64(d)
+	case fv.Kind() >= reflect.Int && fv.Kind() <= reflect.Int64:
+		value = float64(fv.Int())
+		bound, err = strconv.ParseFloat(param, 64)
+	case fv.Kind() >= reflect.Uint && fv.Kind() <= reflect.Uint64:
+		value = float64(fv.Uint())
+		bound, err = strconv.ParseFloat(param, 64)
+	case fv.Kind() == reflect.Float32 || fv.Kind() == reflect.Float64:
+		value = fv.Float()
+		bound, err = strconv.ParseFloat(param, 64)
+	default:
+		l, ok := length(fv)
+		if !ok {
+			return fmt.Errorf("unsupported type %s", fv.Type())
+		}
+		n, err := strconv.Atoi(param)
+		if err != nil {
+			return fmt.Errorf("invalid parameter: %w", err)
+		}
+		if name == "min" && l < n {
+			return fmt.Errorf("length must be at least %d but got %d", n, l)
+		}
+		if name == "max" && l > n {
+			return fmt.Errorf("length must be at most %d but got %d", n, l)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("invalid parameter: %w", err)
+	}
+	if name == "min" && value < bound {
+		return fmt.Errorf("must be at least %s but got %v", param, fv.Interface())
+	}
+	if name == "max" && value > bound {
+		return fmt.Errorf("must be at most %s but got %v", param, fv.Interface())
+	}
+	return nil
+}
+
+func length(fv reflect.Value) (int, bool) {
+	switch fv.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return fv.Len(), true
+	}
+	return 0, false
+}
+
+func isEmpty(fv reflect.Value) bool {
+	if l, ok := length(fv); ok {
+		return l == 0
+	}
+	return fv.IsZero()
+}
@@ -0,0 +1,131 @@
+package config_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kayac/go-config"
+)
+
+type validateServer struct {
+	Host string `yaml:"host" validate:"required"`
+	Port int    `yaml:"port" validate:"min=1,max=65535"`
+}
+
+type validateConf struct {
+	Name    string           `yaml:"name" validate:"required"`
+	Mode    string           `yaml:"mode" validate:"oneof=dev prod"`
+	Timeout time.Duration    `yaml:"timeout" validate:"omitempty,min=1s"`
+	Servers []validateServer `yaml:"servers" validate:"min=1"`
+	Limit   int              `yaml:"limit"`
+}
+
+func (c *validateConf) Validate() error {
+	if c.Limit < len(c.Servers) {
+		return fmt.Errorf("limit %d is less than the number of servers %d", c.Limit, len(c.Servers))
+	}
+	return nil
+}
+
+func TestValidate(t *testing.T) {
+	base, err := genConfigFile("validate_base.yml", `
+name: app
+mode: dev
+limit: 2
+servers:
+  - {host: a.example.com, port: 80}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	local, err := genConfigFile("validate_local.yml", `
+mode: test
+timeout: 100ms
+servers:
+  - {host: a.example.com, port: 80}
+  - {port: 0}
+  - {host: c.example.com, port: 443}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loader := config.New()
+	loader.Validate = true
+	var ok validateConf
+	if err := loader.Load(&ok, base); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	var c validateConf
+	err = loader.Load(&c, base, local)
+	if err == nil {
+		t.Fatal("validation must fail")
+	}
+	t.Log(err)
+	var verr *config.ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("unexpected error type %T", err)
+	}
+	expected := []string{
+		"mode: oneof=dev prod: must be one of [dev prod] but got \"test\" (" + local + ")",
+		"timeout: min=1s: must be at least 1s but got 100ms (" + local + ")",
+		"servers[1].host: required: must not be empty (" + local + ")",
+		"servers[1].port: min=1: must be at least 1 but got 0 (" + local + ")",
+		"limit 2 is less than the number of servers 3",
+	}
+	if len(verr.Errors) != len(expected) {
+		t.Fatalf("unexpected number of errors: %d", len(verr.Errors))
+	}
+	for i, fe := range verr.Errors {
+		if fe.Error() != expected[i] {
+			t.Errorf("unexpected error\n got: %s\nwant: %s", fe, expected[i])
+		}
+	}
+	if !strings.HasPrefix(err.Error(), "validation failed:") {
+		t.Errorf("unexpected error message: %s", err)
+	}
+}
+
+type validateOptionalConf struct {
+	Mode  *string `yaml:"mode" validate:"oneof=a b"`
+	Level *int    `yaml:"level" validate:"required,min=1"`
+	Email string  `yaml:"email" validate:"required,email"`
+}
+
+func TestValidateOptional(t *testing.T) {
+	loader := config.New()
+	loader.Validate = true
+
+	var c validateOptionalConf
+	err := loader.LoadBytes(&c, []byte("email: admin@example.com\n"))
+	var verr *config.ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(verr.Errors) != 1 || verr.Errors[0].Error() != "level: required: must not be empty" {
+		t.Errorf("unexpected errors: %s", err)
+	}
+
+	var c2 validateOptionalConf
+	if err := loader.LoadBytes(&c2, []byte("mode: a\nlevel: 1\nemail: admin@example.com\n")); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	var c3 validateOptionalConf
+	if err := loader.LoadBytes(&c3, []byte("mode: c\nlevel: 0\n")); err == nil {
+		t.Error("validation must fail")
+	} else if !strings.Contains(err.Error(), `mode: oneof=a b: must be one of [a b] but got "c"`) ||
+		!strings.Contains(err.Error(), "level: min=1: must be at least 1 but got 0") ||
+		!strings.Contains(err.Error(), "email: required: must not be empty") {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	var c4 validateConf
+	if err := config.LoadBytes(&c4, []byte("mode: test\n")); err != nil {
+		t.Errorf("validation must be disabled by default: %s", err)
+	}
+}
@@ -0,0 +1,207 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// DefaultWatchInterval is the default interval of polling config files by Watcher.
+var DefaultWatchInterval = 5 * time.Second
+
+// Holder holds a loaded config value and publishes reloaded values atomically.
+type Holder struct {
+	typ   reflect.Type
+	value atomic.Value
+
+	mu          sync.Mutex // serializes reloads
+	subscribers []func(oldConf, newConf interface{})
+}
+
+// NewHolder returns a Holder which holds `conf`, a pointer to a loaded config value.
+// Reloaded values are new values of the same type.
+func NewHolder(conf interface{}) (*Holder, error) {
+	t := reflect.TypeOf(conf)
+	if t == nil || t.Kind() != reflect.Ptr {
+		return nil, fmt.Errorf("config must be a pointer but got %T", conf)
+	}
+	h := &Holder{typ: t}
+	h.value.Store(conf)
+	return h, nil
+}
+
+// Get returns the current config value, a pointer of the same type as the value passed to NewHolder.
+// The returned value must not be modified.
+func (h *Holder) Get() interface{} {
+	return h.value.Load()
+}
+
+// Subscribe registers `fn` called with the old and new values after each successful reload.
+func (h *Holder) Subscribe(fn func(oldConf, newConf interface{})) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.subscribers = append(h.subscribers, fn)
+}
+
+// Reload calls `load` with a new value, and publishes it only if `load` succeeds.
+func (h *Holder) Reload(load func(conf interface{}) error) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	conf := reflect.New(h.typ.Elem()).Interface()
+	if err := load(conf); err != nil {
+		return err
+	}
+	old := h.value.Load()
+	h.value.Store(conf)
+	for _, fn := range h.subscribers {
+		fn(old, conf)
+	}
+	return nil
+}
+
+// Watcher reloads config files with Env when they are modified.
+type Watcher struct {
+	*Holder
+
+	// Interval is the interval of polling files. DefaultWatchInterval is used if zero.
+	Interval time.Duration
+
+	// OnError is called with errors of polling and reloading in Run and ReloadOnSignal, if not nil.
+	OnError func(error)
+
+	// Debounce is the delay of reloading by signals in ReloadOnSignal.
+	// Signals received while waiting restart the delay, and cause a single reload.
+	Debounce time.Duration
+
+	loader   *Loader
+	patterns []string
+	stats    map[string]fileStat
+}
+
+type fileStat struct {
+	modTime int64
+	size    int64
+}
+
+// Watch loads `configPaths` with Env into `conf`, and returns a Watcher which reloads them.
+func Watch(conf interface{}, configPaths ...string) (*Watcher, error) {
+	return defaultLoader.Watch(conf, configPaths...)
+}
+
+// Watch loads `configPaths` with Env in the format detected by their extensions into `conf`,
+// and returns a Watcher which holds `conf`. Glob patterns in `configPaths` are expanded
+// on each poll, so added and removed files are also detected. Files included
+// by templates are not watched.
+func (l *Loader) Watch(conf interface{}, configPaths ...string) (*Watcher, error) {
+	h, err := NewHolder(conf)
+	if err != nil {
+		return nil, err
+	}
+	w := &Watcher{Holder: h, loader: l, patterns: configPaths}
+	if w.stats, err = w.stat(); err != nil {
+		return nil, err
+	}
+	if err := l.LoadWithEnvFiles(conf, configPaths...); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Reload reloads the config files into a new value, and publishes it if loading succeeds.
+func (w *Watcher) Reload() error {
+	return w.Holder.Reload(func(conf interface{}) error {
+		return w.loader.LoadWithEnvFiles(conf, w.patterns...)
+	})
+}
+
+// Run polls the config files and reloads them when modified, until ctx is done.
+// Run returns ctx.Err().
+func (w *Watcher) Run(ctx context.Context) error {
+	interval := w.Interval
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if err := w.poll(); err != nil && w.OnError != nil {
+				w.OnError(err)
+			}
+		}
+	}
+}
+
+// ReloadOnSignal reloads the config files when the process receives `sigs`
+// (SIGHUP if empty), until ctx is done. ReloadOnSignal returns ctx.Err().
+// It can run concurrently with Run.
+func (w *Watcher) ReloadOnSignal(ctx context.Context, sigs ...os.Signal) error {
+	if len(sigs) == 0 {
+		sigs = []os.Signal{syscall.SIGHUP}
+	}
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, sigs...)
+	defer signal.Stop(ch)
+
+	var delay <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ch:
+			if w.Debounce > 0 {
+				delay = time.After(w.Debounce)
+				continue
+			}
+		case <-delay:
+			delay = nil
+		}
+		if err := w.Reload(); err != nil && w.OnError != nil {
+			w.OnError(err)
+		}
+	}
+}
+
+// poll reloads the config files if they are modified since the last poll.
+func (w *Watcher) poll() error {
+	stats, err := w.stat()
+	if err != nil {
+		return err
+	}
+	if reflect.DeepEqual(stats, w.stats) {
+		return nil
+	}
+	w.stats = stats
+	return w.Reload()
+}
+
+func (w *Watcher) stat() (map[string]fileStat, error) {
+	paths, err := w.loader.Glob(w.patterns...)
+	if err != nil {
+		return nil, err
+	}
+	stats := make(map[string]fileStat, len(paths))
+	for _, p := range paths {
+		var fi fs.FileInfo
+		if w.loader.FS == nil {
+			fi, err = os.Stat(p)
+		} else {
+			fi, err = fs.Stat(w.loader.FS, p)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s stat failed: %w", p, err)
+		}
+		stats[p] = fileStat{modTime: fi.ModTime().UnixNano(), size: fi.Size()}
+	}
+	return stats, nil
+}
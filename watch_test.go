@@ -0,0 +1,167 @@
+package config_test
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/kayac/go-config"
+)
+
+func TestWatch(t *testing.T) {
+	path, err := genConfigFile("watch.yml", "domain: example.com\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var conf Conf
+	w, err := config.Watch(&conf, filepath.Join(dir, "watch*.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := w.Get().(*Conf); c != &conf || c.Domain != "example.com" {
+		t.Fatalf("unexpected initial value: %+v", c)
+	}
+	w.Interval = 10 * time.Millisecond
+	errs := make(chan error, 10)
+	w.OnError = func(err error) { errs <- err }
+	updated := make(chan [2]*Conf, 10)
+	w.Subscribe(func(oldConf, newConf interface{}) {
+		updated <- [2]*Conf{oldConf.(*Conf), newConf.(*Conf)}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- w.Run(ctx) }()
+
+	// a broken file is not published
+	if err := os.WriteFile(path, []byte("domain: [\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-errs:
+		t.Log(err)
+	case u := <-updated:
+		t.Fatalf("unexpected update: %+v", u[1])
+	case <-time.After(5 * time.Second):
+		t.Fatal("reload error is not reported")
+	}
+	if c := w.Get().(*Conf); c.Domain != "example.com" {
+		t.Errorf("broken config is published: %+v", c)
+	}
+
+	// an added file is detected
+	if _, err := genConfigFile("watch_local.yml", "is_dev: true\n"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("domain: dev.example.com\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var u [2]*Conf
+	for u[1] == nil || !u[1].IsDev || u[1].Domain != "dev.example.com" {
+		select {
+		case u = <-updated:
+		case <-time.After(5 * time.Second):
+			t.Fatal("config is not reloaded")
+		}
+	}
+	if c := w.Get().(*Conf); c != u[1] {
+		t.Errorf("unexpected current value: %+v", c)
+	}
+	if conf.Domain != "example.com" {
+		t.Errorf("the initial value is modified: %+v", conf)
+	}
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestNewHolder(t *testing.T) {
+	if _, err := config.NewHolder(Conf{}); err == nil {
+		t.Error("expected error for a non-pointer value")
+	}
+}
+
+func TestReloadOnSignal(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("signals are not supported")
+	}
+	path, err := genConfigFile("signal.yml", "domain: example.com\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var conf Conf
+	w, err := config.Watch(&conf, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Debounce = 50 * time.Millisecond
+	errs := make(chan error, 10)
+	w.OnError = func(err error) { errs <- err }
+	reloaded := make(chan *Conf, 10)
+	w.Subscribe(func(_, newConf interface{}) { reloaded <- newConf.(*Conf) })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	// keep the test process alive even if SIGHUP is received before ReloadOnSignal starts
+	guard := make(chan os.Signal, 10)
+	signal.Notify(guard, syscall.SIGHUP)
+	defer signal.Stop(guard)
+	go func() { done <- w.ReloadOnSignal(ctx) }()
+	time.Sleep(100 * time.Millisecond) // wait for signal.Notify
+
+	p, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("domain: dev.example.com\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := p.Signal(syscall.SIGHUP); err != nil {
+			t.Fatal(err)
+		}
+	}
+	select {
+	case c := <-reloaded:
+		if c.Domain != "dev.example.com" {
+			t.Errorf("unexpected value: %+v", c)
+		}
+	case err := <-errs:
+		t.Fatal(err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("config is not reloaded")
+	}
+	select {
+	case c := <-reloaded:
+		t.Errorf("signals are not debounced: %+v", c)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	if err := os.WriteFile(path, []byte("domain: [\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Signal(syscall.SIGHUP); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-errs:
+		t.Log(err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("reload error is not reported")
+	}
+	if c := w.Get().(*Conf); c.Domain != "dev.example.com" {
+		t.Errorf("broken config is published: %+v", c)
+	}
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("unexpected error: %v", err)
+	}
+}